@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mock-server/config"
+	"mock-server/data"
+	"mock-server/faults"
+	"mock-server/mocksgw"
+	"mock-server/reconcile"
+	"mock-server/router"
+	"mock-server/scenario"
+	"net/http"
+)
+
+// registerAdminRoutes wires the operator endpoints that are not part of the MSG API
+func registerAdminRoutes(mux *router.Router, cfg config.Config, couchbaseService *data.Service, mockSGW *mocksgw.Server, scenarioRunner *scenario.Runner, faultInjector *faults.Injector, reconciler *reconcile.Scheduler) {
+
+	//List, add or clear the fault rules for Fineract calls and store operations
+	mux.Get("/api/v3/admin/faults", func(w http.ResponseWriter, r *http.Request) error {
+		return router.JSON(w, http.StatusOK, faultInjector.Rules())
+	})
+
+	mux.Post("/api/v3/admin/faults", func(w http.ResponseWriter, r *http.Request) error {
+		var rule faults.Rule
+		err := json.NewDecoder(r.Body).Decode(&rule)
+		if err != nil {
+			return router.BadRequest(err)
+		}
+
+		added, err := faultInjector.Add(rule)
+		if err != nil {
+			return router.BadRequest(err)
+		}
+
+		log.Println("Added fault rule", added.Id, "for", added.Target, added.Pattern)
+		return router.JSON(w, http.StatusCreated, added)
+	})
+
+	mux.Delete("/api/v3/admin/faults", func(w http.ResponseWriter, r *http.Request) error {
+		faultInjector.Clear()
+		return router.Text(w, http.StatusOK, "Cleared all fault rules")
+	})
+
+	mux.Delete("/api/v3/admin/faults/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		id := router.Param(r, "id")
+		if !faultInjector.Remove(id) {
+			return router.NotFound(fmt.Errorf("no fault rule with id %s", id))
+		}
+		return router.Text(w, http.StatusOK, "Removed fault rule "+id)
+	})
+
+	//Show or switch the scenario the mocks are running
+	mux.Get("/api/v3/admin/scenarios", func(w http.ResponseWriter, r *http.Request) error {
+		return router.Text(w, http.StatusOK, scenarioRunner.Current())
+	})
+
+	mux.Post("/api/v3/admin/scenarios", func(w http.ResponseWriter, r *http.Request) error {
+		var loaded scenario.Scenario
+		err := json.NewDecoder(r.Body).Decode(&loaded)
+		if err != nil {
+			return router.BadRequest(err)
+		}
+
+		err = scenarioRunner.Apply(loaded)
+		if err != nil {
+			return router.BadRequest(err)
+		}
+		return router.Text(w, http.StatusOK, "Applied scenario "+loaded.Name)
+	})
+
+	//Seed the reads bucket with fake clients and groups, ?seed= makes the run reproducible
+	mux.Post("/api/v3/admin/seed", func(w http.ResponseWriter, r *http.Request) error {
+		var options data.PublishOptions
+		err := json.NewDecoder(r.Body).Decode(&options)
+		if err != nil {
+			return router.BadRequest(err)
+		}
+
+		generator, err := generatorFor(r, cfg)
+		if err != nil {
+			return router.BadRequest(err)
+		}
+
+		report, err := couchbaseService.PublishDocs(options, generator)
+		if err != nil {
+			return err
+		}
+		return router.JSON(w, http.StatusOK, report)
+	})
+
+	//Export or import a JSON-lines snapshot of the reads or writes bucket
+	mux.Get("/api/v3/admin/snapshots/{bucket}", func(w http.ResponseWriter, r *http.Request) error {
+		bucket := router.Param(r, "bucket")
+		store, err := couchbaseService.Store(bucket)
+		if err != nil {
+			return router.NotFound(err)
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		exported, err := data.ExportSnapshot(store, w)
+		if err != nil {
+			//the response has started streaming, all that is left is to log
+			log.Println("Snapshot export of", bucket, "failed after", exported, "documents", err)
+			return nil
+		}
+		log.Println("Exported", exported, "documents from", bucket)
+		return nil
+	})
+
+	mux.Post("/api/v3/admin/snapshots/{bucket}", func(w http.ResponseWriter, r *http.Request) error {
+		bucket := router.Param(r, "bucket")
+		store, err := couchbaseService.Store(bucket)
+		if err != nil {
+			return router.NotFound(err)
+		}
+
+		imported, err := data.ImportSnapshot(store, r.Body)
+		if err != nil {
+			return router.BadRequest(fmt.Errorf("imported %d documents before failing: %w", imported, err))
+		}
+		return router.Text(w, http.StatusOK, fmt.Sprintf("Imported %d documents into %s", imported, bucket))
+	})
+
+	//Every call the mock Sync Gateway admin API received, only available with SGW_MODE=mock
+	mux.Get("/api/v3/admin/sgw-calls", func(w http.ResponseWriter, r *http.Request) error {
+		if mockSGW == nil {
+			return router.NotFound(errors.New("sync gateway is not mocked"))
+		}
+		return router.JSON(w, http.StatusOK, mockSGW.Calls())
+	})
+
+	//Latest reconciliation reports between Fineract and the reads bucket, newest first
+	mux.Get("/api/v3/admin/reconciliations", func(w http.ResponseWriter, r *http.Request) error {
+		reports, err := reconciler.Reports()
+		if err != nil {
+			return err
+		}
+
+		return router.JSON(w, http.StatusOK, map[string]interface{}{
+			"officeIds": reconciler.OfficeIds,
+			"interval":  reconciler.Interval.String(),
+			"running":   reconciler.Running(),
+			"reports":   reports,
+		})
+	})
+
+	//Reconcile now, ?dryRun=true only reports the drift without repairing it
+	mux.Post("/api/v3/admin/reconciliations", func(w http.ResponseWriter, r *http.Request) error {
+		err := reconciler.Run(r.URL.Query().Get("dryRun") != "true")
+		if errors.Is(err, reconcile.ErrRunning) {
+			return router.NewError(http.StatusConflict, "reconciliation_running", err)
+		}
+		if err != nil {
+			return err
+		}
+		return router.Text(w, http.StatusAccepted, "Reconciliation started")
+	})
+}
@@ -1,13 +1,17 @@
 package auth
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	//"github.com/golang-jwt/jwt"
-	"github.com/bxcodec/faker/v3"
 	uuid2 "github.com/google/uuid"
 	"log"
+	"mock-server/fakegen"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
@@ -20,6 +24,13 @@ type Service struct {
 	SGWBaseURL string
 	DistrictId string
 	CountryId  int
+	//Generator drives the fake claims and org units, a random one is used when nil
+	Generator   *fakegen.Generator
+	MaxOrgUnits int
+	//OfficeIds are the offices the user covers, channels are granted for each, DistrictId when empty
+	OfficeIds []string
+	//OrgUnits is the user's real office hierarchy, generated org units are used when empty
+	OrgUnits []OU
 }
 
 type OafClaims struct {
@@ -79,35 +90,50 @@ func (s Service) NewService(
 	}
 }
 
+func (s Service) generator() *fakegen.Generator {
+	if s.Generator != nil {
+		return s.Generator
+	}
+	generator, _ := fakegen.New(0, fakegen.DefaultLocale)
+	return generator
+}
+
 func (s Service) generateOrgUnits() []OU {
 	var orgUnits []OU
-	countries := []string{"Kenya", "Rwanda"}
+	generator := s.generator()
+	levelNames := generator.Locale.LevelNames
+
+	maxOrgUnits := s.MaxOrgUnits
+	if maxOrgUnits <= 0 {
+		maxOrgUnits = 5
+	}
+
 	//random number of org units to generate
-	numOrgUnits, _ := faker.RandomInt(1, 5, 1)
-	for i := 0; i < numOrgUnits[0]; i++ {
-		randomId, _ := faker.RandomInt(100, 999, 1)
-		//random number between 1 and 2
-		randomCountry, _ := faker.RandomInt(0, len(countries)-1, 1)
+	numOrgUnits := generator.Between(1, maxOrgUnits)
+	for i := 0; i < numOrgUnits; i++ {
+		randomId := generator.Between(100, 999)
 		parent := 0
-		name := faker.Name()
+		name := generator.Place()
 		isCountry := false
-		levelName := faker.Name()
+		levelName := levelNames[len(levelNames)-1]
+		if i < len(levelNames) {
+			levelName = levelNames[i]
+		}
 		if i == 0 {
-			name = countries[randomCountry[0]]
+			name = generator.Locale.Country
 			isCountry = true
-			levelName = "Country"
 		}
 		if i > 0 {
 			parent = orgUnits[i-1].Id
 		}
-		orgUnits = append(orgUnits, OU{Id: randomId[0], Name: name, IsCountry: isCountry, LevelName: levelName, Parent: parent})
+		orgUnits = append(orgUnits, OU{Id: randomId, Name: name, IsCountry: isCountry, LevelName: levelName, Parent: parent})
 	}
 	return orgUnits
 }
 
 func (s Service) CreateSGWUser(claims *CustomClaims) (SGWResponse, error) {
 	//replace @ with _ in email
-	email := "dev-test_oneacrefund.org" //strings.Replace(claims.Email, "@", "_", -1)
+	email := strings.Replace(claims.Email, "@", "_", -1)
 	uuid := uuid2.New().String()
 	var roles []string
 
@@ -118,7 +144,10 @@ func (s Service) CreateSGWUser(claims *CustomClaims) (SGWResponse, error) {
 
 	var channelList []string
 
-	generatedOus := s.generateOrgUnits()
+	generatedOus := s.OrgUnits
+	if len(generatedOus) == 0 {
+		generatedOus = s.generateOrgUnits()
+	}
 
 	for _, channel := range generatedOus {
 		channelList = append(channelList, strconv.Itoa(channel.Id))
@@ -126,11 +155,19 @@ func (s Service) CreateSGWUser(claims *CustomClaims) (SGWResponse, error) {
 
 	entities := []string{"clients", "groups"}
 
+	officeIds := s.OfficeIds
+	if len(officeIds) == 0 {
+		officeIds = []string{s.DistrictId}
+	}
+
 	var entityChannels []string
 
-	for _, entity := range entities {
-		entityChannels = append(entityChannels, entity+"_"+s.DistrictId)
+	for _, officeId := range officeIds {
+		for _, entity := range entities {
+			entityChannels = append(entityChannels, entity+"_"+officeId)
+		}
 	}
+	entityChannels = append(entityChannels, "offices")
 
 	emailChannel := strings.Replace(claims.Email, "@", "_", 1)
 
@@ -152,31 +189,17 @@ func (s Service) CreateSGWUser(claims *CustomClaims) (SGWResponse, error) {
 		return SGWResponse{}, err
 	}
 
-	var requestBodyString = string(requestBodyJson)
-	//make http call to SGW to create user
-	createUserEndpointReads := s.SGWBaseURL + "/offline_reads/_user/"
-	response, err := http.Post(createUserEndpointReads, "application/json", strings.NewReader(requestBodyString))
+	err = s.putSGWUser("offline_reads", email, requestBodyJson)
 
 	if err != nil {
-		log.Println(err)
-		return SGWResponse{}, err
-	}
-
-	if response.StatusCode != 200 && response.StatusCode != 201 {
-		log.Println("Error creating user on Offline Reads", response.StatusCode)
+		log.Println("Error creating user on Offline Reads", err)
 		return SGWResponse{}, errors.New("error creating offline reads user")
 	}
 
-	createUserEndpointReads = s.SGWBaseURL + "/offline_writes/_user/"
-	response, err = http.Post(createUserEndpointReads, "application/json", strings.NewReader(requestBodyString))
+	err = s.putSGWUser("offline_writes", email, requestBodyJson)
 
 	if err != nil {
-		log.Println(err)
-		return SGWResponse{}, err
-	}
-
-	if response.StatusCode != 200 && response.StatusCode != 201 {
-		log.Println("Error creating user on Offline Writes", response.StatusCode)
+		log.Println("Error creating user on Offline Writes", err)
 		return SGWResponse{}, errors.New("error creating offline writes user")
 	}
 
@@ -191,6 +214,51 @@ func (s Service) CreateSGWUser(claims *CustomClaims) (SGWResponse, error) {
 	return responseBody, nil
 }
 
+// putSGWUser creates the user or, when it already exists, replaces its password and channels,
+// so logging in again refreshes the user instead of failing with a conflict.
+func (s Service) putSGWUser(db string, name string, body []byte) error {
+	endpoint := s.SGWBaseURL + "/" + db + "/_user/" + url.PathEscape(name)
+	request, err := http.NewRequest("PUT", endpoint, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 && response.StatusCode != 201 {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+// PingSGW checks the Sync Gateway admin API answers on its root endpoint.
+func PingSGW(ctx context.Context, sgwBaseURL string) error {
+	request, err := http.NewRequestWithContext(ctx, "GET", sgwBaseURL+"/", nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
 func (s Service) RetrieveClaims() (CustomClaims, error) {
 	//secret := "-----BEGIN CERTIFICATE-----\n" + s.Secret + "\n-----END CERTIFICATE-----"
 	//verifyKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(secret))
@@ -221,13 +289,10 @@ func (s Service) RetrieveClaims() (CustomClaims, error) {
 	claims := CustomClaims{}
 	roles := []string{"replicator", "oaf_dev", "oaf_fo"}
 
-	err := faker.FakeData(&claims)
-
-	if err != nil {
-		log.Println("Error generating fake data", err)
-		return CustomClaims{}, err
-	}
-
+	generator := s.generator()
+	claims.GivenName = generator.FirstName()
+	claims.Surname = generator.LastName()
+	claims.Email = generator.Email(claims.GivenName, claims.Surname, "oneacrefund.org")
 	claims.Role = roles
 
 	return claims, nil
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"mock-server/mocksgw"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateSGWUserTwice(t *testing.T) {
+	sgw := mocksgw.NewServer()
+	httpServer := httptest.NewServer(sgw)
+	defer httpServer.Close()
+
+	service := Service{SGWBaseURL: httpServer.URL, DistrictId: "1"}
+
+	for _, email := range []string{"ada@oneacrefund.org", "ada@oneacrefund.org", "grace@oneacrefund.org"} {
+		claims := CustomClaims{}
+		claims.Email = email
+		created, err := service.CreateSGWUser(&claims)
+
+		if err != nil {
+			t.Fatalf("CreateSGWUser(%s): %v", email, err)
+		}
+		if created.Name != "ada_oneacrefund.org" && created.Name != "grace_oneacrefund.org" {
+			t.Errorf("user name = %q, want one based on %s", created.Name, email)
+		}
+	}
+
+	for _, db := range []string{"offline_reads", "offline_writes"} {
+		if users := sgw.Users(db); len(users) != 2 {
+			t.Errorf("%s users = %v, want 2", db, users)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package cliff
+
+import (
+	"fmt"
+	"log"
+	"mock-server/shared"
+	"strconv"
+)
+
+// clientAddress is the single clientAddress of a client request, with what the device captured.
+func clientAddress(address shared.ClientBodyAddress, locale string) shared.ClientAddress {
+	converted := shared.ClientAddress{
+		Street:       address.Street,
+		AddressLine1: address.AddressLine1,
+		AddressLine2: address.AddressLine2,
+		City:         address.City,
+		Locale:       locale,
+	}
+	if address.PostalCode != 0 {
+		converted.PostalCode = strconv.Itoa(address.PostalCode)
+	}
+	if hasCoordinates(address) {
+		converted.Latitude = strconv.FormatFloat(address.Latitude, 'f', -1, 64)
+		converted.Longitude = strconv.FormatFloat(address.Longitude, 'f', -1, 64)
+	}
+	return converted
+}
+
+// fineractAddresses is the address list of a new client, empty when the device captured no address.
+// A device that doesn't pick an address type gets addressTypeId.
+func fineractAddresses(address shared.ClientBodyAddress, addressTypeId int) []shared.AddressDTO {
+	if address == (shared.ClientBodyAddress{}) {
+		return nil
+	}
+
+	if address.AddressTypeId != 0 {
+		addressTypeId = address.AddressTypeId
+	}
+
+	fineractAddress := shared.AddressDTO{
+		AddressTypeId:   addressTypeId,
+		IsActive:        true,
+		Street:          address.Street,
+		AddressLine1:    address.AddressLine1,
+		AddressLine2:    address.AddressLine2,
+		AddressLine3:    address.AddressLine3,
+		City:            address.City,
+		StateProvinceId: address.StateProvinceId,
+		CountryId:       address.CountryId,
+		Latitude:        address.Latitude,
+		Longitude:       address.Longitude,
+	}
+	if address.PostalCode != 0 {
+		fineractAddress.PostalCode = strconv.Itoa(address.PostalCode)
+	}
+	return []shared.AddressDTO{fineractAddress}
+}
+
+// hasCoordinates is false for the 0,0 devices send when they have no GPS fix.
+func hasCoordinates(address shared.ClientBodyAddress) bool {
+	return address.Latitude != 0 || address.Longitude != 0
+}
+
+// GetClientAddresses lists the addresses of a client.
+func (s Service) GetClientAddresses(clientId string) ([]shared.AddressDTO, error) {
+	var addresses []shared.AddressDTO
+	err := s.getJSON(s.GetClientsEndpoint+"/"+clientId+"/addresses", &addresses)
+	return addresses, err
+}
+
+// UpdateClientAddress saves the address of a device request for an existing client through the
+// addresses endpoint, Fineract ignores addresses sent with the client. The client's address of the
+// same type is updated, or one is added when it has none. An address it already has is skipped.
+func (s Service) UpdateClientAddress(body shared.ParsedClientRequestBody, requestedBy string) (error, int) {
+	addresses := fineractAddresses(body.ClientAddress, s.AddressTypeId)
+	if len(addresses) == 0 {
+		return nil, 200
+	}
+	address := addresses[0]
+
+	clientId := strconv.Itoa(body.FineractClientId)
+	current, err := s.GetClientAddresses(clientId)
+	if err != nil {
+		log.Println(err)
+		return fmt.Errorf("addresses of client %s: %w", clientId, err), 502
+	}
+
+	method := "POST"
+	for _, existing := range current {
+		if existing.AddressTypeId != address.AddressTypeId {
+			continue
+		}
+		if sameAddress(existing, address) {
+			return nil, 200
+		}
+		method = "PUT"
+		address.AddressId = existing.AddressId
+	}
+
+	endpoint := s.GetClientsEndpoint + "/" + clientId + "/addresses?type=" + strconv.Itoa(address.AddressTypeId)
+	statusCode, err := s.send(method, endpoint, address, requestedBy)
+	if err != nil {
+		log.Println("Address of client", clientId, "failed", err)
+		return fmt.Errorf("address: %w", err), statusCode
+	}
+	return nil, 200
+}
+
+// sameAddress compares what a device can capture of an address.
+func sameAddress(existing shared.AddressDTO, requested shared.AddressDTO) bool {
+	return existing.Street == requested.Street &&
+		existing.AddressLine1 == requested.AddressLine1 &&
+		existing.AddressLine2 == requested.AddressLine2 &&
+		existing.AddressLine3 == requested.AddressLine3 &&
+		existing.City == requested.City &&
+		existing.StateProvinceId == requested.StateProvinceId &&
+		existing.CountryId == requested.CountryId &&
+		existing.PostalCode == requested.PostalCode &&
+		existing.Latitude == requested.Latitude &&
+		existing.Longitude == requested.Longitude
+}
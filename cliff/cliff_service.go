@@ -2,16 +2,26 @@ package cliff
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"mock-server/dates"
 	"mock-server/shared"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// OfficerHeader names the officer a write is made for. Fineract ignores it,
+// the mock Fineract uses it to target scenario rules.
+const OfficerHeader = "X-Requested-For"
+
 type Service struct {
 	BaseURL string
 	Token   string
@@ -19,8 +29,19 @@ type Service struct {
 	DefaultOfficeId      string
 	GetClientsEndpoint   string
 	GetGroupsEndpoint    string
+	GetOfficesEndpoint   string
+	GetAuditsEndpoint    string
+	GetCodesEndpoint     string
 	CreateClientEndpoint string
 	UpdateClientEndpoint string
+	//AddressTypeId is the Fineract address type of addresses captured without one
+	AddressTypeId int
+	//Codes validates and translates code values, ids pass through unchecked when nil
+	Codes *CodeCache
+	//HTTPClient is used for every Fineract call, http.DefaultClient when nil
+	HTTPClient *http.Client
+	//PingClient is used by Ping instead, so readiness skips fault injection and recording
+	PingClient *http.Client
 }
 
 type WebhookRequestOffice struct {
@@ -60,6 +81,9 @@ func NewCliffService(
 	defaultOfficeId string,
 	getClientsEndpoint string,
 	getGroupsEndpoint string,
+	getOfficesEndpoint string,
+	getAuditsEndpoint string,
+	getCodesEndpoint string,
 	createClientEndpoint string,
 	updateClientEndpoint string,
 
@@ -70,11 +94,21 @@ func NewCliffService(
 		DefaultOfficeId:      defaultOfficeId,
 		GetClientsEndpoint:   getClientsEndpoint,
 		GetGroupsEndpoint:    getGroupsEndpoint,
+		GetOfficesEndpoint:   getOfficesEndpoint,
+		GetAuditsEndpoint:    getAuditsEndpoint,
+		GetCodesEndpoint:     getCodesEndpoint,
 		CreateClientEndpoint: createClientEndpoint,
 		UpdateClientEndpoint: updateClientEndpoint,
 	}
 }
 
+func (s Service) httpClient() *http.Client {
+	if s.HTTPClient != nil {
+		return s.HTTPClient
+	}
+	return http.DefaultClient
+}
+
 func getCliffRequest(url string, method string, token string) (*http.Request, error) {
 	httpRequest, err := http.NewRequest(method, url, nil)
 
@@ -89,6 +123,35 @@ func getCliffRequest(url string, method string, token string) (*http.Request, er
 	return httpRequest, nil
 }
 
+// Ping checks Fineract is reachable and accepts the token.
+func (s Service) Ping(ctx context.Context) error {
+	request, err := getCliffRequest(s.BaseURL+s.GetClientsEndpoint+"?limit=1", "GET", s.Token)
+	if err != nil {
+		return err
+	}
+
+	client := s.PingClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 401 || resp.StatusCode == 403 {
+		return fmt.Errorf("token rejected with status %d", resp.StatusCode)
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 func (s Service) GetClientById(clientId string) (shared.ClientDTO, error) {
 	request, err := getCliffRequest(s.BaseURL+s.GetClientsEndpoint+"/"+clientId, "GET", s.Token)
 	if err != nil {
@@ -96,9 +159,7 @@ func (s Service) GetClientById(clientId string) (shared.ClientDTO, error) {
 		return shared.ClientDTO{}, err
 	}
 
-	client := http.Client{}
-
-	resp, err := client.Do(request)
+	resp, err := s.httpClient().Do(request)
 
 	if err != nil {
 		log.Println(err)
@@ -114,6 +175,11 @@ func (s Service) GetClientById(clientId string) (shared.ClientDTO, error) {
 		return shared.ClientDTO{}, err
 	}
 
+	if resp.StatusCode != 200 {
+		log.Println("Bad Status Code: ", resp.StatusCode)
+		return shared.ClientDTO{}, errors.New(string(body))
+	}
+
 	var clientResponse shared.ClientDTO
 	err = json.Unmarshal(body, &clientResponse)
 
@@ -122,36 +188,75 @@ func (s Service) GetClientById(clientId string) (shared.ClientDTO, error) {
 		return shared.ClientDTO{}, err
 	}
 
+	err = s.fillDetails(&clientResponse)
+
+	if err != nil {
+		log.Println(err)
+		return shared.ClientDTO{}, err
+	}
+
 	return clientResponse, nil
 }
 
-func (s Service) UpsertClient(body shared.ParsedClientRequestBody, method string) (shared.CreateClientResponse, error, int) {
-	cliffClientRequestCreate := convertCbClientToCliffClient(body, s.DefaultOfficeId)
-	//cliffClientRequestUpdate := convertCbClientToCliffUpdateClient(body, s.DefaultOfficeId)
+// UpdateClient sends the bio of a device request for an existing client,
+// then its address and identifier changes.
+func (s Service) UpdateClient(body shared.ParsedClientRequestBody, requestedBy string) (shared.CreateClientResponse, error, int) {
+	update, err := convertCbClientToCliffUpdateClient(body, s.DefaultOfficeId, s.Codes)
+	if err != nil {
+		log.Println(err)
+		return shared.CreateClientResponse{}, err, requestErrorStatus(err)
+	}
 
-	cliffClientRequestCreateBody, err := json.Marshal(cliffClientRequestCreate)
+	clientId := strconv.Itoa(body.FineractClientId)
+	statusCode, err := s.send("PUT", s.UpdateClientEndpoint+"/"+clientId, update, requestedBy)
+	if err != nil {
+		log.Println("Update of client", clientId, "failed", err)
+		return shared.CreateClientResponse{}, err, statusCode
+	}
+
+	err, statusCode = s.UpdateClientAddress(body, requestedBy)
+	if err != nil {
+		//the client update is idempotent, a retry sends it again along with the address
+		return shared.CreateClientResponse{}, fmt.Errorf("client updated, %w", err), statusCode
+	}
+
+	if body.ClientId.DocumentKey == "" && len(body.ClientIdentifiers) == 0 {
+		return shared.CreateClientResponse{ClientId: body.FineractClientId, ResourceId: body.FineractClientId}, nil, 200
+	}
+
+	response, err, statusCode := s.UpdateClientIdentifiers(body, requestedBy)
+	if err != nil {
+		//the client update is idempotent, a retry sends it again along with the identifiers left
+		err = fmt.Errorf("client updated, %w", err)
+	}
+	return response, err, statusCode
+}
+
+func (s Service) UpsertClient(body shared.ParsedClientRequestBody, method string, requestedBy string) (shared.CreateClientResponse, error, int) {
+	if body.FineractClientId != 0 {
+		return s.UpdateClient(body, requestedBy)
+	}
+
+	cliffClientRequestCreate, err := convertCbClientToCliffClient(body, s.DefaultOfficeId, s.AddressTypeId, s.Codes)
 	if err != nil {
 		log.Println(err)
-		return shared.CreateClientResponse{}, err, 400
+		return shared.CreateClientResponse{}, err, requestErrorStatus(err)
 	}
-	//cliffClientRequestUpdateBody, err := json.Marshal(cliffClientRequestUpdate)
+
+	cliffClientRequestCreateBody, err := json.Marshal(cliffClientRequestCreate)
 	if err != nil {
 		log.Println(err)
 		return shared.CreateClientResponse{}, err, 400
 	}
 
-	//clientId := "45"
 	log.Println("Cliff Client Request Body: ", string(cliffClientRequestCreateBody))
 	url := s.BaseURL + s.CreateClientEndpoint
-	//if method == "PUT" {
-	//	url = s.BaseURL + s.UpdateClientEndpoint + "/" + clientId
-	//}
 	request, err := getCliffRequest(url, method, s.Token)
+	if err == nil && requestedBy != "" {
+		request.Header.Add(OfficerHeader, requestedBy)
+	}
 
 	bodyBuffer := bytes.NewBuffer(cliffClientRequestCreateBody)
-	//if method == "PUT" {
-	//	bodyBuffer = bytes.NewBuffer(cliffClientRequestUpdateBody)
-	//}
 	request.Body = ioutil.NopCloser(bodyBuffer)
 
 	if err != nil {
@@ -159,8 +264,7 @@ func (s Service) UpsertClient(body shared.ParsedClientRequestBody, method string
 		return shared.CreateClientResponse{}, err, 400
 	}
 
-	client := http.Client{}
-	resp, err := client.Do(request)
+	resp, err := s.httpClient().Do(request)
 
 	if err != nil {
 		log.Println(err)
@@ -200,119 +304,442 @@ func (s Service) UpsertClient(body shared.ParsedClientRequestBody, method string
 	return response, nil, 200
 }
 
-func convertCbClientToCliffUpdateClient(body shared.ParsedClientRequestBody, officeId string) shared.ClientUpdateBody {
-	longitude := "0.0"
-	latitude := "0.0"
-
-	address := shared.ClientAddress{
-		Street:       "N/A",
-		AddressLine1: "N/A",
-		AddressLine2: "N/A",
-		CloseTown:    "N/A",
-		VillageName:  "N/A",
-		CellName:     "N/A",
-		PostalCode:   "N/A",
-		City:         "N/A",
-		Latitude:     longitude,
-		Longitude:    latitude,
-		Locale:       body.ClientBio.Locale,
-	}
-	//todays date in format 27 January 2022
-	return shared.ClientUpdateBody{
-		ClientAddress:  address,
-		LegalFormId:    1,
-		Firstname:      body.ClientBio.Firstname,
-		Lastname:       body.ClientBio.Lastname,
-		MobileNo:       body.ClientBio.PrimaryPhoneNumber,
-		Locale:         body.ClientBio.Locale,
-		Active:         true,
-		DateFormat:     "dd MMMM yyyy",
-		ActivationDate: "05 January 2022",
+// requestErrorStatus is the status of a device request that couldn't be converted, 503 while
+// the code tables aren't loaded as the same request goes through once they are.
+func requestErrorStatus(err error) int {
+	if errors.Is(err, ErrCodesUnavailable) {
+		return http.StatusServiceUnavailable
 	}
+	return http.StatusBadRequest
 }
 
-func convertCbClientToCliffClient(body shared.ParsedClientRequestBody, officeId string) shared.CreateClientDTO {
+// requestDates converts the activation date and date of birth of a device request into
+// Fineract's RequestFormat. Missing dates stay empty, so an update leaves them as they are.
+func requestDates(bio shared.ClientBodyBio) (string, string, error) {
+	activationDate, err := requestDate(bio.ActivationDate, bio)
+	if err != nil {
+		return "", "", fmt.Errorf("activationDate: %w", err)
+	}
+
+	dateOfBirth, err := requestDate(bio.DateOfBirth, bio)
+	if err != nil {
+		return "", "", fmt.Errorf("dateOfBirth: %w", err)
+	}
+
+	return activationDate, dateOfBirth, nil
+}
+
+func requestDate(value string, bio shared.ClientBodyBio) (string, error) {
+	date, err := dates.ParseRequest(value, bio.DateFormat, bio.Locale)
+	if err != nil || date.IsZero() {
+		return "", err
+	}
+	return dates.Format(date, dates.RequestFormat)
+}
+
+// requestCodes are the code value ids of a device request.
+type requestCodes struct {
+	legalFormId            int
+	genderId               int
+	clientTypeId           int
+	clientClassificationId int
+}
+
+// resolveRequestCodes validates the code values of a device request and translates them into Fineract ids.
+func resolveRequestCodes(body shared.ParsedClientRequestBody, codes *CodeCache) (requestCodes, error) {
+	var resolved requestCodes
+	var err error
+
+	resolved.legalFormId, err = ResolveLegalForm(body.ClientBio.LegalForm)
+	if err != nil {
+		return requestCodes{}, fmt.Errorf("legalForm: %w", err)
+	}
+
+	resolved.genderId, err = codes.Resolve(CodeGender, body.ClientBio.GenderId)
+	if err != nil {
+		return requestCodes{}, fmt.Errorf("genderId: %w", err)
+	}
+
+	resolved.clientTypeId, err = codes.Resolve(CodeClientType, body.ClientBio.ClientTypeId)
+	if err != nil {
+		return requestCodes{}, fmt.Errorf("clientTypeId: %w", err)
+	}
+
+	resolved.clientClassificationId, err = codes.Resolve(CodeClientClassification, body.ClientBio.ClientClassificationId)
+	if err != nil {
+		return requestCodes{}, fmt.Errorf("clientClassificationId: %w", err)
+	}
+
+	return resolved, nil
+}
+
+// requestLocale is the locale sent with a request, Fineract wants one whenever there's a dateFormat.
+func requestLocale(locale string) string {
+	if locale == "" {
+		return "en"
+	}
+	return locale
+}
+
+func convertCbClientToCliffUpdateClient(body shared.ParsedClientRequestBody, officeId string, codes *CodeCache) (shared.ClientUpdateBody, error) {
+	activationDate, dateOfBirth, err := requestDates(body.ClientBio)
+	if err != nil {
+		return shared.ClientUpdateBody{}, err
+	}
+
+	resolved, err := resolveRequestCodes(body, codes)
+	if err != nil {
+		return shared.ClientUpdateBody{}, err
+	}
+
+	//an update without a legal form keeps the client's instead of resetting it to the default
+	if strings.TrimSpace(body.ClientBio.LegalForm) == "" {
+		resolved.legalFormId = 0
+	}
+
+	return shared.ClientUpdateBody{
+		LegalFormId:            resolved.legalFormId,
+		Firstname:              body.ClientBio.Firstname,
+		Lastname:               body.ClientBio.Lastname,
+		MobileNo:               body.ClientBio.PrimaryPhoneNumber,
+		Locale:                 requestLocale(body.ClientBio.Locale),
+		Active:                 true,
+		DateFormat:             dates.RequestFormat,
+		ActivationDate:         activationDate,
+		DateOfBirth:            dateOfBirth,
+		GenderId:               resolved.genderId,
+		ClientTypeId:           resolved.clientTypeId,
+		ClientClassificationId: resolved.clientClassificationId,
+	}, nil
+}
+
+func convertCbClientToCliffClient(body shared.ParsedClientRequestBody, officeId string, addressTypeId int, codes *CodeCache) (shared.CreateClientDTO, error) {
+	activationDate, dateOfBirth, err := requestDates(body.ClientBio)
+	if err != nil {
+		return shared.CreateClientDTO{}, err
+	}
+
+	//an active client needs an activation date, a new one without is activated today
+	if activationDate == "" {
+		activationDate, err = dates.Format(time.Now(), dates.RequestFormat)
+		if err != nil {
+			return shared.CreateClientDTO{}, err
+		}
+	}
+
+	resolved, err := resolveRequestCodes(body, codes)
+	if err != nil {
+		return shared.CreateClientDTO{}, err
+	}
+
 	officeIdInt, err := strconv.Atoi(officeId)
 	if err != nil {
 		log.Println(err)
 		officeIdInt = 240
 	}
-	identifier := shared.ClientIdentifier{
-		DocumentTypeId: body.ClientId.DocumentTypeId,
-		DocumentKey:    body.ClientId.DocumentKey,
-		Status:         "ACTIVE",
-	}
-	longitude := "0.0"
-	latitude := "0.0"
-
-	address := shared.ClientAddress{
-		Street:       "N/A",
-		AddressLine1: "N/A",
-		AddressLine2: "N/A",
-		CloseTown:    "N/A",
-		VillageName:  "N/A",
-		CellName:     "N/A",
-		PostalCode:   "N/A",
-		City:         "N/A",
-		Latitude:     longitude,
-		Longitude:    latitude,
-		Locale:       body.ClientBio.Locale,
-	}
-	//todays date in format 27 January 2022
+
+	changes, err := requestIdentifierChanges(body, codes)
+	if err != nil {
+		return shared.CreateClientDTO{}, err
+	}
+
+	identifiers, err := newClientIdentifiers(changes)
+	if err != nil {
+		return shared.CreateClientDTO{}, err
+	}
+
+	address := clientAddress(body.ClientAddress, body.ClientBio.Locale)
+
 	return shared.CreateClientDTO{
-		ClientAddress:  address,
-		OfficeId:       officeIdInt,
-		FamilyMembers:  []interface{}{},
-		LegalFormId:    1,
-		Firstname:      body.ClientBio.Firstname,
-		Lastname:       body.ClientBio.Lastname,
-		MobileNo:       body.ClientBio.PrimaryPhoneNumber,
-		Locale:         body.ClientBio.Locale,
-		Active:         true,
-		DateFormat:     "dd MMMM yyyy",
-		DateOfBirth:    "01 January 1990",
-		ActivationDate: "05 January 2022",
-		Identifiers:    []shared.ClientIdentifier{identifier},
+		ClientAddress:          address,
+		OfficeId:               officeIdInt,
+		FamilyMembers:          []interface{}{},
+		LegalFormId:            resolved.legalFormId,
+		Firstname:              body.ClientBio.Firstname,
+		Lastname:               body.ClientBio.Lastname,
+		MobileNo:               body.ClientBio.PrimaryPhoneNumber,
+		Locale:                 requestLocale(body.ClientBio.Locale),
+		Active:                 true,
+		DateFormat:             dates.RequestFormat,
+		DateOfBirth:            dateOfBirth,
+		ActivationDate:         activationDate,
+		Identifiers:            identifiers,
+		Address:                fineractAddresses(body.ClientAddress, addressTypeId),
+		GenderId:               resolved.genderId,
+		ClientTypeId:           resolved.clientTypeId,
+		ClientClassificationId: resolved.clientClassificationId,
+	}, nil
+}
+
+// pageSize is how many clients or groups are requested per page from Fineract.
+const pageSize = 200
+
+// pageResponse is the envelope of Fineract's paged list endpoints.
+type pageResponse[T any] struct {
+	TotalFilteredRecords int `json:"totalFilteredRecords"`
+	PageItems            []T `json:"pageItems"`
+}
+
+// detailsConcurrency is how many clients have their details fetched at once.
+const detailsConcurrency = 8
+
+// ClientDetailsError is a client left out of GetOfficeClients because its addresses
+// or identifiers couldn't be fetched.
+type ClientDetailsError struct {
+	Client shared.ClientDTO
+	Err    error
+}
+
+// GetOfficeClients lists the clients of an office with their details. Clients whose details
+// fail are returned apart, so one client doesn't fail the office and isn't synced without them.
+func (s *Service) GetOfficeClients(ctx context.Context, officeId string) ([]shared.ClientDTO, []ClientDetailsError, error) {
+	clients, err := getPages[shared.ClientDTO](s, s.GetClientsEndpoint, url.Values{"officeId": {officeId}})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.withDetails(ctx, clients)
+}
+
+// withDetails fills in the addresses, identifiers and code value names of each client,
+// Fineract only returns addresses and identifiers one client at a time.
+func (s Service) withDetails(ctx context.Context, clients []shared.ClientDTO) ([]shared.ClientDTO, []ClientDetailsError, error) {
+	errs := make([]error, len(clients))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < detailsConcurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = s.fillDetails(&clients[i])
+			}
+		}()
+	}
+
+	for i := range clients {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	var detailed []shared.ClientDTO
+	var failed []ClientDetailsError
+	for i, client := range clients {
+		if errs[i] != nil {
+			failed = append(failed, ClientDetailsError{Client: client, Err: errs[i]})
+			continue
+		}
+		detailed = append(detailed, client)
+	}
+	return detailed, failed, nil
+}
+
+func (s Service) fillDetails(client *shared.ClientDTO) error {
+	clientId := strconv.Itoa(client.Id)
+
+	addresses, err := s.GetClientAddresses(clientId)
+	if err != nil {
+		return fmt.Errorf("addresses of client %d: %w", client.Id, err)
+	}
+
+	identifiers, err := s.GetClientIdentifiers(clientId)
+	if err != nil {
+		return fmt.Errorf("identifiers of client %d: %w", client.Id, err)
+	}
+
+	client.Addresses = addresses
+	client.Identifiers = identifiers
+	s.Codes.fillNames(client)
+	return nil
+}
+
+// GetOfficeGroups lists the groups of an office. Unlike /clients, /groups only answers
+// with the page envelope when asked to with paged=true, otherwise it's a plain array.
+func (s *Service) GetOfficeGroups(officeId string) ([]shared.GroupDTO, error) {
+	return getPages[shared.GroupDTO](s, s.GetGroupsEndpoint, url.Values{"officeId": {officeId}, "paged": {"true"}})
+}
+
+// auditTimeFormat is the format of the makerDateTimeFrom audit filter.
+const auditTimeFormat = "2006-01-02 15:04:05"
+
+// clientAuditEntities are the audited entities that change a synced client.
+// Identifier and address audits name their client in clientId.
+var clientAuditEntities = []string{"CLIENT", "CLIENTIDENTIFIER", "ADDRESS"}
+
+// clientTransferActions move a client to another office. They are audited under the office
+// the client left, so the office it joined only finds them through GetClientTransfers.
+var clientTransferActions = []string{"PROPOSEANDACCEPTTRANSFER", "ACCEPTTRANSFER"}
+
+// GetClientAudits lists the successful client, client identifier and address commands
+// made in an office since a point in time, with ClientId set on every entry.
+func (s *Service) GetClientAudits(officeId string, since time.Time) ([]shared.AuditDTO, error) {
+	var audits []shared.AuditDTO
+	for _, entityName := range clientAuditEntities {
+		query := auditQuery(since)
+		query.Set("entityName", entityName)
+		query.Set("officeId", officeId)
+
+		entries, err := getPages[shared.AuditDTO](s, s.GetAuditsEndpoint, query)
+		if err != nil {
+			return nil, fmt.Errorf("%s audits: %w", entityName, err)
+		}
+		audits = append(audits, entries...)
+	}
+	return withClientIds(audits), nil
+}
+
+// GetClientTransfers lists the client transfers of every office since a point in time.
+func (s *Service) GetClientTransfers(since time.Time) ([]shared.AuditDTO, error) {
+	var audits []shared.AuditDTO
+	for _, actionName := range clientTransferActions {
+		query := auditQuery(since)
+		query.Set("entityName", "CLIENT")
+		query.Set("actionName", actionName)
+
+		entries, err := getPages[shared.AuditDTO](s, s.GetAuditsEndpoint, query)
+		if err != nil {
+			return nil, fmt.Errorf("%s audits: %w", actionName, err)
+		}
+		audits = append(audits, entries...)
 	}
+	return withClientIds(audits), nil
 }
 
-func (s *Service) GetOfficeClients(officeId string) ([]shared.ClientDTO, error) {
+func auditQuery(since time.Time) url.Values {
+	return url.Values{
+		"makerDateTimeFrom": {since.UTC().Format(auditTimeFormat)},
+		"processingResult":  {"1"},
+		"paged":             {"true"},
+	}
+}
+
+// withClientIds fills ClientId of client audits, which only carry it as the resource id.
+func withClientIds(audits []shared.AuditDTO) []shared.AuditDTO {
+	for i := range audits {
+		if audits[i].ClientId == 0 && audits[i].EntityName == "CLIENT" {
+			audits[i].ClientId = audits[i].ResourceId
+		}
+	}
+	return audits
+}
+
+// getPages follows offset/limit paging until every record matching query is fetched.
+func getPages[T any](s *Service, endpoint string, query url.Values) ([]T, error) {
+
+	client := s.httpClient()
+	var items []T
 
-	client := &http.Client{}
-	url := s.BaseURL + s.GetClientsEndpoint + "?officeId=" + officeId
-	httpRequest, err := http.NewRequest("GET", url, nil)
+	for {
+		query.Set("offset", strconv.Itoa(len(items)))
+		query.Set("limit", strconv.Itoa(pageSize))
+		httpRequest, err := getCliffRequest(s.BaseURL+endpoint+"?"+query.Encode(), "GET", s.Token)
+
+		if err != nil {
+			return nil, err
+		}
+
+		//perform request
+		httpResponse, err := client.Do(httpRequest)
+
+		//handle error
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+
+		if httpResponse.StatusCode != 200 {
+			body, _ := ioutil.ReadAll(httpResponse.Body)
+			httpResponse.Body.Close()
+			log.Println("Bad Status Code: ", httpResponse.StatusCode)
+			return nil, fmt.Errorf("status %d: %s", httpResponse.StatusCode, body)
+		}
+
+		//unmarshal response
+		var response pageResponse[T]
+		err = json.NewDecoder(httpResponse.Body).Decode(&response)
+		httpResponse.Body.Close()
+
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+
+		//handle response
+		items = append(items, response.PageItems...)
+
+		if len(response.PageItems) == 0 || len(items) >= response.TotalFilteredRecords {
+			return items, nil
+		}
+	}
+}
+
+// GetOffices lists every office, Fineract doesn't page this endpoint.
+func (s *Service) GetOffices() ([]shared.OfficeDTO, error) {
+	request, err := getCliffRequest(s.BaseURL+s.GetOfficesEndpoint, "GET", s.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient().Do(request)
 
 	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
-	httpRequest.Header.Add("Authorization", "Bearer "+s.Token)
-	httpRequest.Header.Add("Content-Type", "application/json")
-	httpRequest.Header.Add("fineract-platform-tenantid", "default")
+	defer resp.Body.Close()
 
-	//perform request
-	httpResponse, err := client.Do(httpRequest)
+	body, err := ioutil.ReadAll(resp.Body)
 
-	//handle error
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	defer httpResponse.Body.Close()
+	if resp.StatusCode != 200 {
+		log.Println("Bad Status Code: ", resp.StatusCode)
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
 
-	//unmarshal response
-	var response shared.ClientsResponseDTO
-	err = json.NewDecoder(httpResponse.Body).Decode(&response)
+	var offices []shared.OfficeDTO
+	err = json.Unmarshal(body, &offices)
 
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	//handle response
-	clients := response.PageItems
+	return offices, nil
+}
+
+// OfficeSubtree returns the root office and every office below it, root first.
+func OfficeSubtree(offices []shared.OfficeDTO, rootId int) ([]shared.OfficeDTO, error) {
+	children := map[int][]shared.OfficeDTO{}
+	var root *shared.OfficeDTO
+
+	for i := range offices {
+		if offices[i].Id == rootId {
+			root = &offices[i]
+			continue
+		}
+		children[offices[i].ParentId] = append(children[offices[i].ParentId], offices[i])
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("office %d not found", rootId)
+	}
 
-	return clients, nil
+	subtree := []shared.OfficeDTO{*root}
+	for i := 0; i < len(subtree); i++ {
+		subtree = append(subtree, children[subtree[i].Id]...)
+	}
+	return subtree, nil
 }
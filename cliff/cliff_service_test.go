@@ -0,0 +1,54 @@
+package cliff
+
+import (
+	"mock-server/shared"
+	"reflect"
+	"testing"
+)
+
+func TestOfficeSubtree(t *testing.T) {
+	//1 is the head office, 2 and 3 are regions, 4 and 5 branches of region 2
+	offices := []shared.OfficeDTO{
+		{Id: 4, ParentId: 2},
+		{Id: 1},
+		{Id: 3, ParentId: 1},
+		{Id: 2, ParentId: 1},
+		{Id: 5, ParentId: 2},
+	}
+
+	tests := []struct {
+		name    string
+		rootId  int
+		want    []int
+		wantErr bool
+	}{
+		{name: "head office", rootId: 1, want: []int{1, 3, 2, 4, 5}},
+		{name: "region", rootId: 2, want: []int{2, 4, 5}},
+		{name: "leaf", rootId: 5, want: []int{5}},
+		{name: "unknown office", rootId: 9, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subtree, err := OfficeSubtree(offices, test.rootId)
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("OfficeSubtree(%d) = %v, want an error", test.rootId, subtree)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []int
+			for _, office := range subtree {
+				ids = append(ids, office.Id)
+			}
+			if !reflect.DeepEqual(ids, test.want) {
+				t.Errorf("OfficeSubtree(%d) = %v, want %v", test.rootId, ids, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package cliff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mock-server/shared"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fineract code tables the cache loads.
+const (
+	CodeGender               = "Gender"
+	CodeClientType           = "ClientType"
+	CodeClientClassification = "ClientClassification"
+	CodeDocumentType         = "Customer Identifier"
+	CodeAddressType          = "ADDRESS_TYPE"
+)
+
+// LegalForms is Fineract's legalForm enum, it isn't a code table.
+var LegalForms = map[int]string{
+	1: "PERSON",
+	2: "ENTITY",
+}
+
+// ErrCodesUnavailable is returned when a value is given by name before its code table was loaded.
+var ErrCodesUnavailable = errors.New("code values not loaded from Fineract")
+
+type Code struct {
+	Id            int    `json:"id"`
+	Name          string `json:"name"`
+	SystemDefined bool   `json:"systemDefined"`
+}
+
+type CodeValue struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Position    int    `json:"position"`
+	Description string `json:"description"`
+	Active      bool   `json:"active"`
+	Mandatory   bool   `json:"mandatory"`
+}
+
+// CodeCache keeps the values of Fineract code tables, refreshed every interval,
+// to validate and translate the values devices send and the ones Fineract returns.
+type CodeCache struct {
+	Cliff *Service
+	Codes []string
+
+	//OnRefresh is called after every successful refresh, e.g. to resend requests that waited for the values
+	OnRefresh func()
+
+	mu          sync.RWMutex
+	values      map[string][]CodeValue
+	refreshedAt time.Time
+	stop        chan struct{}
+}
+
+func NewCodeCache(service *Service) *CodeCache {
+	return &CodeCache{
+		Cliff:  service,
+		Codes:  []string{CodeGender, CodeClientType, CodeClientClassification, CodeDocumentType, CodeAddressType},
+		values: map[string][]CodeValue{},
+	}
+}
+
+// Refresh reloads every code table, a code missing from Fineract is left out.
+// On failure the values loaded before are kept.
+func (c *CodeCache) Refresh() error {
+	codes, err := c.Cliff.GetCodes()
+
+	if err != nil {
+		return err
+	}
+
+	byName := map[string]int{}
+	for _, code := range codes {
+		byName[code.Name] = code.Id
+	}
+
+	values := map[string][]CodeValue{}
+	for _, name := range c.Codes {
+		codeId, ok := byName[name]
+		if !ok {
+			continue
+		}
+
+		codeValues, err := c.Cliff.GetCodeValues(codeId)
+		if err != nil {
+			return fmt.Errorf("code %s: %w", name, err)
+		}
+		values[name] = codeValues
+	}
+
+	c.mu.Lock()
+	c.values = values
+	c.refreshedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// startupLoadTimeout is how long Start waits for the first load, so requests
+// made right after startup can already resolve code values by name.
+const startupLoadTimeout = 10 * time.Second
+
+// Start loads the code tables right away and then every interval until Stop.
+// It returns once the first load finished or after startupLoadTimeout.
+func (c *CodeCache) Start(interval time.Duration) {
+	c.stop = make(chan struct{})
+	stop := c.stop
+	loaded := make(chan struct{})
+
+	go func() {
+		c.refresh()
+		close(loaded)
+
+		if interval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.refresh()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-loaded:
+	case <-time.After(startupLoadTimeout):
+		log.Println("Fineract code values not loaded yet, requests naming code values are left pending until they are")
+	}
+}
+
+func (c *CodeCache) Stop() {
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+func (c *CodeCache) refresh() {
+	err := c.Refresh()
+
+	if err != nil {
+		log.Println("Couldn't refresh Fineract code values:", err)
+		return
+	}
+	log.Println("Refreshed Fineract code values")
+
+	if c.OnRefresh != nil {
+		c.OnRefresh()
+	}
+}
+
+// Values returns the active values of a code, false when it isn't loaded.
+func (c *CodeCache) Values(code string) ([]CodeValue, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	values, ok := c.values[code]
+	if !ok {
+		return nil, false
+	}
+
+	var active []CodeValue
+	for _, value := range values {
+		if value.Active {
+			active = append(active, value)
+		}
+	}
+	return active, true
+}
+
+// Resolve turns a device value into a code value id. The value is an id, a name or
+// a prefix of a single name ("M" for "Male"), names are case insensitive.
+// An empty value is 0. Ids pass through unchecked while the code isn't loaded.
+func (c *CodeCache) Resolve(code string, value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	values, loaded := c.Values(code)
+	id, err := strconv.Atoi(value)
+
+	if !loaded {
+		if err == nil {
+			return id, nil
+		}
+		return 0, fmt.Errorf("%s %q: %w", code, value, ErrCodesUnavailable)
+	}
+
+	var prefixed []CodeValue
+	for _, codeValue := range values {
+		if (err == nil && codeValue.Id == id) || strings.EqualFold(codeValue.Name, value) {
+			return codeValue.Id, nil
+		}
+		if strings.HasPrefix(strings.ToLower(codeValue.Name), strings.ToLower(value)) {
+			prefixed = append(prefixed, codeValue)
+		}
+	}
+
+	if err != nil && len(prefixed) == 1 {
+		return prefixed[0].Id, nil
+	}
+
+	return 0, fmt.Errorf("unknown %s %q", code, value)
+}
+
+// Name returns the name of a code value id, empty when unknown.
+func (c *CodeCache) Name(code string, id int) string {
+	if c == nil || id == 0 {
+		return ""
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, codeValue := range c.values[code] {
+		if codeValue.Id == id {
+			return codeValue.Name
+		}
+	}
+	return ""
+}
+
+// fillNames sets the names of a client's code values Fineract left out, from the cache.
+func (c *CodeCache) fillNames(client *shared.ClientDTO) {
+	if client.Gender.Name == "" {
+		client.Gender.Name = c.Name(CodeGender, client.Gender.Id)
+	}
+	if client.ClientType.Name == "" {
+		client.ClientType.Name = c.Name(CodeClientType, client.ClientType.Id)
+	}
+	if client.ClientClassification.Name == "" {
+		client.ClientClassification.Name = c.Name(CodeClientClassification, client.ClientClassification.Id)
+	}
+}
+
+// ResolveLegalForm turns a device legal form, an id or a LegalForms name, into its id.
+// Clients are people unless the device says otherwise.
+func ResolveLegalForm(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 1, nil
+	}
+
+	for id, name := range LegalForms {
+		if strconv.Itoa(id) == value || strings.EqualFold(name, value) {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown legal form %q", value)
+}
+
+// GetCodes lists Fineract's code tables.
+func (s Service) GetCodes() ([]Code, error) {
+	var codes []Code
+	err := s.getJSON(s.GetCodesEndpoint, &codes)
+	return codes, err
+}
+
+// GetCodeValues lists every value of a code table, inactive ones included.
+func (s Service) GetCodeValues(codeId int) ([]CodeValue, error) {
+	var values []CodeValue
+	err := s.getJSON(s.GetCodesEndpoint+"/"+strconv.Itoa(codeId)+"/codevalues", &values)
+	return values, err
+}
+
+// getJSON decodes the response of an unpaged Fineract GET.
+func (s Service) getJSON(endpoint string, value interface{}) error {
+	request, err := getCliffRequest(s.BaseURL+endpoint, "GET", s.Token)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.httpClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, value)
+}
@@ -0,0 +1,221 @@
+package cliff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mock-server/shared"
+	"strconv"
+	"strings"
+)
+
+// identifierChange is an identifier of a device request resolved for Fineract.
+type identifierChange struct {
+	id         int
+	removed    bool
+	identifier shared.ClientIdentifier
+}
+
+// requestIdentifierChanges resolves the identifiers of a device request, the single clientId
+// of older devices first, skipping it when it has no document key.
+func requestIdentifierChanges(body shared.ParsedClientRequestBody, codes *CodeCache) ([]identifierChange, error) {
+	var requested []shared.ClientBodyIdentifier
+	if body.ClientId.DocumentKey != "" {
+		requested = append(requested, body.ClientId)
+	}
+	requested = append(requested, body.ClientIdentifiers...)
+
+	var changes []identifierChange
+	for i, identifier := range requested {
+		change := identifierChange{id: identifier.Id, removed: identifier.Removed}
+
+		if identifier.Removed {
+			if identifier.Id == 0 {
+				return nil, fmt.Errorf("identifier %d: removing an identifier needs its id", i)
+			}
+			changes = append(changes, change)
+			continue
+		}
+
+		if strings.TrimSpace(identifier.DocumentKey) == "" {
+			return nil, fmt.Errorf("identifier %d: documentKey is required", i)
+		}
+
+		documentType := identifier.DocumentType
+		if identifier.DocumentTypeId != 0 {
+			documentType = strconv.Itoa(identifier.DocumentTypeId)
+		}
+		documentTypeId, err := codes.Resolve(CodeDocumentType, documentType)
+		if err != nil {
+			return nil, fmt.Errorf("identifier %d documentTypeId: %w", i, err)
+		}
+		if documentTypeId == 0 {
+			return nil, fmt.Errorf("identifier %d: documentTypeId is required", i)
+		}
+
+		status, err := identifierStatus(identifier.Status)
+		if err != nil {
+			return nil, fmt.Errorf("identifier %d: %w", i, err)
+		}
+
+		change.identifier = shared.ClientIdentifier{
+			DocumentTypeId: documentTypeId,
+			DocumentKey:    strings.TrimSpace(identifier.DocumentKey),
+			Status:         status,
+			Description:    identifier.Description,
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// identifierStatus is the status Fineract expects, identifiers are active unless the device says otherwise.
+func identifierStatus(value string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "", "ACTIVE":
+		return "ACTIVE", nil
+	case "INACTIVE":
+		return "INACTIVE", nil
+	}
+	return "", fmt.Errorf("unknown identifier status %q", value)
+}
+
+// newClientIdentifiers are the identifiers sent with a new client, which can't reference existing ones.
+func newClientIdentifiers(changes []identifierChange) ([]shared.ClientIdentifier, error) {
+	identifiers := []shared.ClientIdentifier{}
+	for _, change := range changes {
+		if change.id != 0 {
+			return nil, errors.New("a new client has no identifiers to update or remove")
+		}
+		identifiers = append(identifiers, change.identifier)
+	}
+	return identifiers, nil
+}
+
+// GetClientIdentifiers lists the identifiers of a client.
+func (s Service) GetClientIdentifiers(clientId string) ([]shared.IdentifierDTO, error) {
+	var identifiers []shared.IdentifierDTO
+	err := s.getJSON(s.GetClientsEndpoint+"/"+clientId+"/identifiers", &identifiers)
+	return identifiers, err
+}
+
+// UpdateClientIdentifiers adds, updates and removes the identifiers of the existing client a
+// device request names, in request order. Changes the client already has are skipped, so a
+// request retried after a partial failure doesn't create duplicates. It stops at the first
+// change Fineract rejects, the error names the changes applied before it.
+func (s Service) UpdateClientIdentifiers(body shared.ParsedClientRequestBody, requestedBy string) (shared.CreateClientResponse, error, int) {
+	changes, err := requestIdentifierChanges(body, s.Codes)
+	if err != nil {
+		log.Println(err)
+		return shared.CreateClientResponse{}, err, requestErrorStatus(err)
+	}
+
+	clientId := strconv.Itoa(body.FineractClientId)
+	current, err := s.GetClientIdentifiers(clientId)
+	if err != nil {
+		log.Println(err)
+		return shared.CreateClientResponse{}, fmt.Errorf("identifiers of client %s: %w", clientId, err), 502
+	}
+
+	endpoint := s.GetClientsEndpoint + "/" + clientId + "/identifiers"
+	var applied []string
+
+	for i, change := range changes {
+		if alreadyApplied(change, current) {
+			applied = append(applied, strconv.Itoa(i))
+			continue
+		}
+
+		method := "POST"
+		url := endpoint
+		var payload interface{} = change.identifier
+
+		switch {
+		case change.removed:
+			method = "DELETE"
+			url = endpoint + "/" + strconv.Itoa(change.id)
+			payload = nil
+		case change.id != 0:
+			method = "PUT"
+			url = endpoint + "/" + strconv.Itoa(change.id)
+		}
+
+		statusCode, err := s.send(method, url, payload, requestedBy)
+		if err != nil {
+			log.Println("Identifier change", i, "of client", body.FineractClientId, "failed", err)
+			if len(applied) > 0 {
+				err = fmt.Errorf("%w, identifiers %s were applied", err, strings.Join(applied, ", "))
+			}
+			return shared.CreateClientResponse{}, fmt.Errorf("identifier %d: %w", i, err), statusCode
+		}
+		applied = append(applied, strconv.Itoa(i))
+	}
+
+	return shared.CreateClientResponse{ClientId: body.FineractClientId, ResourceId: body.FineractClientId}, nil, 200
+}
+
+// alreadyApplied reports whether the client's current identifiers already reflect a change:
+// a removed identifier is gone, an updated one matches and a new one exists with its type and key.
+func alreadyApplied(change identifierChange, current []shared.IdentifierDTO) bool {
+	for _, identifier := range current {
+		switch {
+		case change.removed || change.id != 0:
+			if identifier.Id != change.id {
+				continue
+			}
+			return !change.removed && sameIdentifier(identifier, change.identifier)
+		case identifier.DocumentType.Id == change.identifier.DocumentTypeId && identifier.DocumentKey == change.identifier.DocumentKey:
+			return true
+		}
+	}
+	return change.removed
+}
+
+func sameIdentifier(identifier shared.IdentifierDTO, requested shared.ClientIdentifier) bool {
+	//Fineract returns the status as clientIdentifierStatusType.active
+	status := identifier.Status
+	if dot := strings.LastIndex(status, "."); dot >= 0 {
+		status = status[dot+1:]
+	}
+
+	return identifier.DocumentType.Id == requested.DocumentTypeId &&
+		identifier.DocumentKey == requested.DocumentKey &&
+		identifier.Description == requested.Description &&
+		strings.EqualFold(status, requested.Status)
+}
+
+// send makes a Fineract write on behalf of an officer, the payload is sent as JSON unless nil.
+func (s Service) send(method string, endpoint string, payload interface{}, requestedBy string) (int, error) {
+	request, err := getCliffRequest(s.BaseURL+endpoint, method, s.Token)
+	if err != nil {
+		return 400, err
+	}
+
+	if requestedBy != "" {
+		request.Header.Add(OfficerHeader, requestedBy)
+	}
+
+	if payload != nil {
+		content, err := json.Marshal(payload)
+		if err != nil {
+			return 400, err
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(content))
+		request.ContentLength = int64(len(content))
+	}
+
+	resp, err := s.httpClient().Do(request)
+	if err != nil {
+		return 400, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, errors.New(string(respBody))
+	}
+	return resp.StatusCode, nil
+}
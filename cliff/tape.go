@@ -0,0 +1,197 @@
+package cliff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Exchange is one recorded Fineract request/response pair.
+type Exchange struct {
+	RecordedAt      time.Time   `json:"recordedAt"`
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"requestHeaders"`
+	RequestBody     string      `json:"requestBody"`
+	StatusCode      int         `json:"statusCode"`
+	ResponseHeaders http.Header `json:"responseHeaders"`
+	ResponseBody    string      `json:"responseBody"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// RecordingTransport writes every exchange to Dir as its own JSON file, with the bearer token redacted.
+type RecordingTransport struct {
+	Dir  string
+	Next http.RoundTripper
+	mu   sync.Mutex
+	seq  int
+}
+
+func (t *RecordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	var requestBody []byte
+	if request.Body != nil {
+		var err error
+		requestBody, err = ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	response, err := next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	headers := request.Header.Clone()
+	if headers.Get("Authorization") != "" {
+		headers.Set("Authorization", "Bearer [REDACTED]")
+	}
+
+	exchange := Exchange{
+		RecordedAt:      time.Now(),
+		Method:          request.Method,
+		URL:             request.URL.RequestURI(),
+		RequestHeaders:  headers,
+		RequestBody:     string(requestBody),
+		StatusCode:      response.StatusCode,
+		ResponseHeaders: response.Header,
+		ResponseBody:    string(responseBody),
+	}
+
+	err = t.save(exchange)
+	if err != nil {
+		log.Println("Couldn't record Fineract exchange", err)
+	}
+
+	return response, nil
+}
+
+func (t *RecordingTransport) save(exchange Exchange) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := os.MkdirAll(t.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	t.seq += 1
+	name := fmt.Sprintf("%s-%05d-%s%s.json", exchange.RecordedAt.Format("20060102T150405"), t.seq, exchange.Method, unsafeFileChars.ReplaceAllString(exchange.URL, "_"))
+	return ioutil.WriteFile(filepath.Join(t.Dir, name), content, 0644)
+}
+
+// ReplayTransport answers requests from a recorded tape. Exchanges for the same method and URL
+// are served in recording order, the last one repeating once they run out.
+type ReplayTransport struct {
+	mu        sync.Mutex
+	exchanges map[string][]Exchange
+	served    map[string]int
+}
+
+func LoadTape(dir string) (*ReplayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	//file names start with the recording time and sequence, so this is recording order
+	sort.Strings(files)
+
+	transport := &ReplayTransport{
+		exchanges: map[string][]Exchange{},
+		served:    map[string]int{},
+	}
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var exchange Exchange
+		err = json.Unmarshal(content, &exchange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange %s: %w", file, err)
+		}
+
+		key := exchange.Method + " " + exchange.URL
+		transport.exchanges[key] = append(transport.exchanges[key], exchange)
+	}
+
+	log.Println("Loaded", len(files), "recorded Fineract exchanges from", dir)
+	return transport, nil
+}
+
+func (t *ReplayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		request.Body.Close()
+	}
+
+	key := request.Method + " " + request.URL.RequestURI()
+
+	t.mu.Lock()
+	exchanges := t.exchanges[key]
+	index := t.served[key]
+	t.served[key] += 1
+	t.mu.Unlock()
+
+	statusCode := http.StatusNotFound
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	body := fmt.Sprintf(`{"httpStatusCode":"404","defaultUserMessage":"no recorded exchange for %s"}`, key)
+
+	if len(exchanges) > 0 {
+		if index >= len(exchanges) {
+			index = len(exchanges) - 1
+		}
+		exchange := exchanges[index]
+		statusCode = exchange.StatusCode
+		if exchange.ResponseHeaders != nil {
+			header = exchange.ResponseHeaders.Clone()
+		}
+		body = exchange.ResponseBody
+	} else {
+		log.Println("No recorded exchange for", key)
+	}
+
+	header.Del("Content-Length")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mock-server/cliff"
+	"mock-server/config"
+	"mock-server/data"
+	"os"
+	"strings"
+)
+
+func runCommand(name string, args []string, cfg config.Config, couchbaseService *data.Service, cliffService *cliff.Service) error {
+	switch name {
+	case "seed":
+		return runSeedCommand(args, cfg, couchbaseService)
+	case "replay-requests":
+		return runReplayRequestsCommand(args, couchbaseService, cliffService)
+	case "migrate-dates":
+		return runMigrateDatesCommand(couchbaseService)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// runSeedCommand publishes fake clients and groups and prints the report, e.g.
+//
+//	mock-server seed -clients 500 -groups 20 -channels clients_240,groups_240 -concurrency 8 -batch 50
+func runSeedCommand(args []string, cfg config.Config, couchbaseService *data.Service) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	clients := flags.Int("clients", 20, "number of clients to publish")
+	groups := flags.Int("groups", 20, "number of groups to publish, clients are spread across them")
+	channels := flags.String("channels", "", "comma separated channels, the ones containing clients/groups are used")
+	officeId := flags.Int("office", 0, "office id set on the documents, also used to derive channels")
+	concurrency := flags.Int("concurrency", 4, "number of concurrent writers")
+	batchSize := flags.Int("batch", 25, "documents per batch upsert")
+	seed := flags.Int64("seed", cfg.FakeSeed, "fake data seed, random when 0")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	generator, err := newGenerator(cfg, *seed)
+	if err != nil {
+		return err
+	}
+
+	options := data.PublishOptions{
+		Clients:     *clients,
+		Groups:      *groups,
+		OfficeId:    *officeId,
+		Concurrency: *concurrency,
+		BatchSize:   *batchSize,
+	}
+	if *channels != "" {
+		options.Channels = strings.Split(*channels, ",")
+	}
+
+	report, err := couchbaseService.PublishDocs(options, generator)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// runMigrateDatesCommand converts the array activation dates of documents synced by earlier
+// versions into ISO dates and prints the report, e.g.
+//
+//	mock-server migrate-dates
+func runMigrateDatesCommand(couchbaseService *data.Service) error {
+	report, err := couchbaseService.MigrateActivationDates()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// runReplayRequestsCommand loads ApiRequest documents captured from a device sync queue,
+// one JSON document per line, and pushes each through Fineract in file order, e.g.
+//
+//	mock-server replay-requests -file requests.jsonl
+func runReplayRequestsCommand(args []string, couchbaseService *data.Service, cliffService *cliff.Service) error {
+	flags := flag.NewFlagSet("replay-requests", flag.ContinueOnError)
+	file := flags.String("file", "requests.jsonl", "JSON-lines file of ApiRequest documents")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	writes, err := couchbaseService.Store("writes")
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+
+	line, succeeded, failed := 0, 0, 0
+	for scanner.Scan() {
+		line += 1
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+
+		var apiRequest data.ApiRequest
+		err = json.Unmarshal(content, &apiRequest)
+		if err == nil && apiRequest.Id == "" {
+			err = errors.New("missing id")
+		}
+		if err != nil {
+			failed += 1
+			fmt.Printf("line %d: skipped, %s\n", line, err)
+			continue
+		}
+
+		//store the line as captured so fields this server doesn't know about survive
+		err = writes.Upsert(apiRequest.Id, json.RawMessage(content))
+		if err != nil {
+			failed += 1
+			fmt.Printf("line %d: %s not stored, %s\n", line, apiRequest.Id, err)
+			continue
+		}
+
+		processed, err := couchbaseService.ProcessApiRequestSync(apiRequest.Id, cliffService)
+		if err != nil {
+			failed += 1
+			fmt.Printf("line %d: %s not processed, %s\n", line, apiRequest.Id, err)
+			continue
+		}
+
+		if processed.ResponseStatusCode >= 400 {
+			failed += 1
+		} else {
+			succeeded += 1
+		}
+		fmt.Printf("line %d: %s %s -> %d %s\n", line, apiRequest.Id, processed.Verb, processed.ResponseStatusCode, processed.ResponseData)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	fmt.Printf("replayed %d requests, %d succeeded, %d failed\n", succeeded+failed, succeeded, failed)
+	return nil
+}
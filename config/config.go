@@ -0,0 +1,409 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"io"
+	"io/ioutil"
+	"mock-server/fakegen"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the effective server configuration. Every key is resolved, lowest priority first,
+// from the defaults, the config file, the .env file, the environment and the command line.
+type Config struct {
+	Secret              string
+	SGWBaseURL          string
+	DistrictId          string
+	ServerPort          string
+	CouchbaseURL        string
+	CouchbaseReadsDB    string
+	CouchbaseWritesDB   string
+	CouchbaseUser       string
+	CouchbasePass       string
+	CliffToken          string
+	CliffBaseURL        string
+	DefaultOfficeId     string
+	AddressTypeId       int
+	StoreDriver         string
+	StoreDir            string
+	CliffMode           string
+	CliffFixture        string
+	SGWMode             string
+	Mode                string
+	FakeSeed            int64
+	FakeLocale          string
+	FakeClients         int
+	FakeGroups          int
+	FakeMaxOrgUnits     int
+	ScenarioFile        string
+	CliffTapeDir        string
+	ShutdownTimeout     time.Duration
+	ReconcileInterval   time.Duration
+	ReconcileOffices    []string
+	CodeRefreshInterval time.Duration
+
+	values  map[string]string
+	sources map[string]string
+}
+
+const (
+	SourceDefault = "default"
+	SourceFile    = "config file"
+	SourceDotEnv  = ".env"
+	SourceEnv     = "environment"
+	SourceFlag    = "flag"
+)
+
+// key describes one setting. Legacy is the name the .env file used before this package.
+type key struct {
+	Name    string
+	Legacy  string
+	Default string
+	Secret  bool
+	Usage   string
+	Check   func(value string) error
+	field   func(c *Config) interface{}
+}
+
+var keys = []key{
+	{Name: "SECRET", Secret: true, Usage: "secret the login JWT is signed with", field: func(c *Config) interface{} { return &c.Secret }},
+	{Name: "SGW_BASE_URL", Usage: "Sync Gateway admin url", Check: checkURL, field: func(c *Config) interface{} { return &c.SGWBaseURL }},
+	{Name: "DISTRICT_ID", Usage: "district id", Check: checkId, field: func(c *Config) interface{} { return &c.DistrictId }},
+	{Name: "SERVER_PORT", Default: "3001", Usage: "port the server listens on", Check: checkPort, field: func(c *Config) interface{} { return &c.ServerPort }},
+	{Name: "COUCHBASE_URL", Usage: "Couchbase host", field: func(c *Config) interface{} { return &c.CouchbaseURL }},
+	{Name: "COUCHBASE_READS_DB", Legacy: "CB_DB", Usage: "reads bucket", field: func(c *Config) interface{} { return &c.CouchbaseReadsDB }},
+	{Name: "COUCHBASE_WRITES_DB", Legacy: "CB_WRITES_DB", Usage: "writes bucket", field: func(c *Config) interface{} { return &c.CouchbaseWritesDB }},
+	{Name: "COUCHBASE_USER", Legacy: "CB_USER", Usage: "Couchbase user", field: func(c *Config) interface{} { return &c.CouchbaseUser }},
+	{Name: "COUCHBASE_PASS", Legacy: "CB_PASS", Secret: true, Usage: "Couchbase password", field: func(c *Config) interface{} { return &c.CouchbasePass }},
+	{Name: "CLIFF_TOKEN", Secret: true, Usage: "Fineract bearer token", field: func(c *Config) interface{} { return &c.CliffToken }},
+	{Name: "CLIFF_BASE_URL", Usage: "Fineract base url", Check: checkURL, field: func(c *Config) interface{} { return &c.CliffBaseURL }},
+	{Name: "DEFAULT_OFFICE_ID", Usage: "office initialized by default", Check: checkId, field: func(c *Config) interface{} { return &c.DefaultOfficeId }},
+	{Name: "ADDRESS_TYPE_ID", Default: "1", Usage: "Fineract address type of addresses captured without one", Check: checkId, field: func(c *Config) interface{} { return &c.AddressTypeId }},
+	{Name: "STORE_DRIVER", Default: "couchbase", Usage: "couchbase, memory or file", Check: oneOf("couchbase", "memory", "file"), field: func(c *Config) interface{} { return &c.StoreDriver }},
+	{Name: "STORE_DIR", Default: "store", Usage: "directory of the file store", field: func(c *Config) interface{} { return &c.StoreDir }},
+	{Name: "CLIFF_MODE", Default: "live", Usage: "live, mock, record or replay", Check: oneOf("live", "mock", "record", "replay"), field: func(c *Config) interface{} { return &c.CliffMode }},
+	{Name: "CLIFF_FIXTURE", Usage: "fixture file seeding the mock Fineract", field: func(c *Config) interface{} { return &c.CliffFixture }},
+	{Name: "SGW_MODE", Default: "live", Usage: "live or mock", Check: oneOf("live", "mock"), field: func(c *Config) interface{} { return &c.SGWMode }},
+	{Name: "MODE", Usage: "all-mock runs without any external service", Check: oneOf("all-mock"), field: func(c *Config) interface{} { return &c.Mode }},
+	{Name: "FAKE_SEED", Usage: "fake data seed, random when empty", field: func(c *Config) interface{} { return &c.FakeSeed }},
+	{Name: "FAKE_LOCALE", Default: fakegen.DefaultLocale, Usage: "fake data locale", Check: checkLocale, field: func(c *Config) interface{} { return &c.FakeLocale }},
+	{Name: "FAKE_CLIENTS", Default: "50", Usage: "clients seeded into the mock Fineract", field: func(c *Config) interface{} { return &c.FakeClients }},
+	{Name: "FAKE_GROUPS", Default: "5", Usage: "groups seeded into the mock Fineract", field: func(c *Config) interface{} { return &c.FakeGroups }},
+	{Name: "FAKE_MAX_ORG_UNITS", Default: "5", Usage: "maximum organization units per login", field: func(c *Config) interface{} { return &c.FakeMaxOrgUnits }},
+	{Name: "SCENARIO_FILE", Usage: "scenario applied at startup", field: func(c *Config) interface{} { return &c.ScenarioFile }},
+	{Name: "CLIFF_TAPE_DIR", Default: "tapes", Usage: "directory of recorded Fineract traffic", field: func(c *Config) interface{} { return &c.CliffTapeDir }},
+	{Name: "SHUTDOWN_TIMEOUT", Default: "25s", Usage: "time allowed to drain on shutdown", field: func(c *Config) interface{} { return &c.ShutdownTimeout }},
+	{Name: "RECONCILE_INTERVAL", Usage: "how often offices are reconciled with Fineract, disabled when empty", field: func(c *Config) interface{} { return &c.ReconcileInterval }},
+	{Name: "CODE_REFRESH_INTERVAL", Default: "1h", Usage: "how often Fineract code values are reloaded and pending requests resent, only at startup when 0", field: func(c *Config) interface{} { return &c.CodeRefreshInterval }},
+	{Name: "RECONCILE_OFFICE_IDS", Usage: "comma separated offices to reconcile, DEFAULT_OFFICE_ID when empty", Check: checkIds, field: func(c *Config) interface{} { return &c.ReconcileOffices }},
+}
+
+// ValidationError lists every missing or invalid key at once.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Load resolves the configuration from args, the command line without the program name.
+// The arguments left after the flags are returned for subcommands. The config is returned
+// alongside a *ValidationError so it can still be printed.
+func Load(args []string) (Config, []string, error) {
+	c := Config{
+		values:  map[string]string{},
+		sources: map[string]string{},
+	}
+
+	for _, k := range keys {
+		c.values[k.Name] = k.Default
+		c.sources[k.Name] = SourceDefault
+	}
+
+	flags := flag.NewFlagSet("mock-server", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "JSON config file, also read from CONFIG_FILE")
+	flagValues := map[string]*string{}
+	for _, k := range keys {
+		flagValues[k.Name] = flags.String(FlagName(k.Name), "", k.Usage)
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return c, nil, err
+	}
+
+	var problems []string
+
+	if *configFile != "" {
+		fileValues, err := readConfigFile(*configFile)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		c.layer(fileValues, SourceFile, &problems)
+	}
+
+	dotEnvValues, err := readDotEnv()
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	c.layer(dotEnvValues, SourceDotEnv, &problems)
+
+	envValues := map[string]string{}
+	for _, k := range keys {
+		if value, ok := os.LookupEnv(k.Name); ok && value != "" {
+			envValues[k.Name] = value
+		}
+	}
+	c.layer(envValues, SourceEnv, &problems)
+
+	cliValues := map[string]string{}
+	flags.Visit(func(f *flag.Flag) {
+		for _, k := range keys {
+			if f.Name == FlagName(k.Name) {
+				cliValues[k.Name] = *flagValues[k.Name]
+			}
+		}
+	})
+	c.layer(cliValues, SourceFlag, &problems)
+
+	c.applyMode()
+
+	for _, k := range keys {
+		value := c.values[k.Name]
+		if value == "" {
+			if c.required(k.Name) {
+				problems = append(problems, fmt.Sprintf("%s is required", k.Name))
+			}
+			continue
+		}
+		err = assign(k.field(&c), value)
+		if err == nil && k.Check != nil {
+			err = k.Check(value)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s (from %s): %v", k.Name, c.sources[k.Name], err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return c, flags.Args(), &ValidationError{Problems: problems}
+	}
+	return c, flags.Args(), nil
+}
+
+// FlagName is the command line flag of a key, e.g. SERVER_PORT is -server-port.
+func FlagName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+// layer overrides values with the ones set in a source, translating legacy names.
+func (c *Config) layer(values map[string]string, source string, problems *[]string) {
+	for name, value := range values {
+		k, ok := lookup(name)
+		if !ok {
+			if source == SourceFile {
+				*problems = append(*problems, fmt.Sprintf("%s: unknown key %s", source, name))
+			}
+			continue
+		}
+		if name == k.Legacy {
+			if _, hasCurrent := values[k.Name]; hasCurrent {
+				continue
+			}
+		}
+		c.values[k.Name] = value
+		c.sources[k.Name] = source
+	}
+}
+
+func lookup(name string) (key, bool) {
+	for _, k := range keys {
+		if k.Name == name || (k.Legacy != "" && k.Legacy == name) {
+			return k, true
+		}
+	}
+	return key{}, false
+}
+
+// applyMode expands MODE=all-mock into the individual mock settings,
+// so the server runs without Couchbase, Fineract or Sync Gateway.
+func (c *Config) applyMode() {
+	if c.values["MODE"] != "all-mock" {
+		return
+	}
+	if c.values["STORE_DRIVER"] == "" || c.values["STORE_DRIVER"] == "couchbase" {
+		c.override("STORE_DRIVER", "memory")
+	}
+	c.override("CLIFF_MODE", "mock")
+	c.override("SGW_MODE", "mock")
+}
+
+func (c *Config) override(name string, value string) {
+	c.values[name] = value
+	c.sources[name] = "MODE=all-mock"
+}
+
+// required reports whether a key must be set given the selected store and modes.
+func (c *Config) required(name string) bool {
+	switch name {
+	case "SECRET", "DISTRICT_ID", "SERVER_PORT", "DEFAULT_OFFICE_ID":
+		return true
+	case "SGW_BASE_URL":
+		return c.values["SGW_MODE"] != "mock"
+	case "COUCHBASE_URL", "COUCHBASE_READS_DB", "COUCHBASE_WRITES_DB", "COUCHBASE_USER", "COUCHBASE_PASS":
+		return c.UsesCouchbase()
+	case "CLIFF_TOKEN", "CLIFF_BASE_URL":
+		//the mock Fineract and replayed tapes need neither a token nor a base url
+		return c.values["CLIFF_MODE"] != "mock" && c.values["CLIFF_MODE"] != "replay"
+	}
+	return false
+}
+
+func (c Config) UsesCouchbase() bool {
+	return c.values["STORE_DRIVER"] == "" || c.values["STORE_DRIVER"] == "couchbase"
+}
+
+// Print writes the effective configuration and where each value came from, with secrets masked.
+func (c Config) Print(w io.Writer) {
+	for _, k := range keys {
+		value := c.values[k.Name]
+		if k.Secret && value != "" {
+			value = "********"
+		}
+		fmt.Fprintf(w, "%-20s %-40s (%s)\n", k.Name, value, c.sources[k.Name])
+	}
+}
+
+// readConfigFile reads a JSON object of key names to values.
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var raw map[string]interface{}
+	err = decoder.Decode(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	for name, value := range raw {
+		values[name] = fmt.Sprint(value)
+	}
+	return values, nil
+}
+
+// readDotEnv reads the first .env file found next to or above the working directory.
+func readDotEnv() (map[string]string, error) {
+	for _, envFile := range []string{".env", "../.env"} {
+		_, err := os.Stat(envFile)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return godotenv.Read(envFile)
+	}
+	return nil, nil
+}
+
+func checkURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q is not an http(s) url", value)
+	}
+	return nil
+}
+
+func checkPort(value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("%q is not a port", value)
+	}
+	return nil
+}
+
+func checkId(value string) error {
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 1 {
+		return fmt.Errorf("%q is not a positive numeric id", value)
+	}
+	return nil
+}
+
+func checkIds(value string) error {
+	for _, id := range strings.Split(value, ",") {
+		err := checkId(strings.TrimSpace(id))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func oneOf(allowed ...string) func(value string) error {
+	return func(value string) error {
+		for _, candidate := range allowed {
+			if value == candidate {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q must be one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+func checkLocale(value string) error {
+	if _, ok := fakegen.Locales[strings.ToLower(value)]; !ok {
+		return fmt.Errorf("unknown locale %q", value)
+	}
+	return nil
+}
+
+// assign parses value into the type of the Config field target points at.
+func assign(target interface{}, value string) error {
+	switch field := target.(type) {
+	case *string:
+		*field = value
+	case *[]string:
+		*field = nil
+		for _, item := range strings.Split(value, ",") {
+			*field = append(*field, strings.TrimSpace(item))
+		}
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("%q is not a non-negative number", value)
+		}
+		*field = parsed
+	case *int64:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	default:
+		return fmt.Errorf("unsupported config field %T", target)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolate runs Load in an empty directory with none of the keys in the environment.
+func isolate(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	t.Setenv("CONFIG_FILE", "")
+	for _, k := range keys {
+		t.Setenv(k.Name, "")
+		if k.Legacy != "" {
+			t.Setenv(k.Legacy, "")
+		}
+	}
+	return dir
+}
+
+func TestLoadLayering(t *testing.T) {
+	base := map[string]string{
+		"SECRET":            "s",
+		"DISTRICT_ID":       "1",
+		"DEFAULT_OFFICE_ID": "1",
+		"MODE":              "all-mock",
+	}
+
+	tests := []struct {
+		name       string
+		file       string
+		dotEnv     string
+		env        map[string]string
+		args       []string
+		key        string
+		wantValue  string
+		wantSource string
+	}{
+		{
+			name:       "default",
+			key:        "SERVER_PORT",
+			wantValue:  "3001",
+			wantSource: SourceDefault,
+		},
+		{
+			name:       "file over default",
+			file:       `{"SERVER_PORT": 4000}`,
+			key:        "SERVER_PORT",
+			wantValue:  "4000",
+			wantSource: SourceFile,
+		},
+		{
+			name:       ".env over file",
+			file:       `{"SERVER_PORT": 4000}`,
+			dotEnv:     "SERVER_PORT=4001\n",
+			key:        "SERVER_PORT",
+			wantValue:  "4001",
+			wantSource: SourceDotEnv,
+		},
+		{
+			name:       "environment over .env",
+			dotEnv:     "SERVER_PORT=4001\n",
+			env:        map[string]string{"SERVER_PORT": "4002"},
+			key:        "SERVER_PORT",
+			wantValue:  "4002",
+			wantSource: SourceEnv,
+		},
+		{
+			name:       "flag over environment",
+			env:        map[string]string{"SERVER_PORT": "4002"},
+			args:       []string{"-server-port", "4003"},
+			key:        "SERVER_PORT",
+			wantValue:  "4003",
+			wantSource: SourceFlag,
+		},
+		{
+			name:       "legacy name",
+			dotEnv:     "CB_DB=legacy\n",
+			key:        "COUCHBASE_READS_DB",
+			wantValue:  "legacy",
+			wantSource: SourceDotEnv,
+		},
+		{
+			name:       "current name wins over legacy in the same source",
+			dotEnv:     "CB_DB=legacy\nCOUCHBASE_READS_DB=current\n",
+			key:        "COUCHBASE_READS_DB",
+			wantValue:  "current",
+			wantSource: SourceDotEnv,
+		},
+		{
+			name:       "all-mock overrides the modes",
+			args:       []string{"-cliff-mode", "live"},
+			key:        "CLIFF_MODE",
+			wantValue:  "mock",
+			wantSource: "MODE=all-mock",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := isolate(t)
+
+			for name, value := range base {
+				t.Setenv(name, value)
+			}
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			if test.file != "" {
+				path := filepath.Join(dir, "config.json")
+				err := os.WriteFile(path, []byte(test.file), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("CONFIG_FILE", path)
+			}
+
+			if test.dotEnv != "" {
+				err := os.WriteFile(filepath.Join(dir, ".env"), []byte(test.dotEnv), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c, _, err := Load(test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.values[test.key] != test.wantValue || c.sources[test.key] != test.wantSource {
+				t.Errorf("%s = %q (%s), want %q (%s)", test.key, c.values[test.key], c.sources[test.key], test.wantValue, test.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	isolate(t)
+	t.Setenv("MODE", "all-mock")
+	t.Setenv("SERVER_PORT", "70000")
+
+	_, _, err := Load(nil)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("err = %v, want a *ValidationError", err)
+	}
+
+	want := map[string]bool{
+		"SECRET is required":                                    false,
+		"DISTRICT_ID is required":                               false,
+		"DEFAULT_OFFICE_ID is required":                         false,
+		`SERVER_PORT (from environment): "70000" is not a port`: false,
+	}
+	for _, problem := range validationErr.Problems {
+		if _, ok := want[problem]; ok {
+			want[problem] = true
+		}
+	}
+	for problem, found := range want {
+		if !found {
+			t.Errorf("missing problem %q in %v", problem, validationErr.Problems)
+		}
+	}
+}
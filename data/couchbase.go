@@ -1,15 +1,21 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/bxcodec/faker/v3"
 	"github.com/couchbase/gocb/v2"
+	"io"
 	"log"
 	"mock-server/cliff"
+	"mock-server/dates"
+	"mock-server/faults"
 	"mock-server/shared"
+	"mock-server/workers"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,8 +27,11 @@ type Service struct {
 	CouchbasePass     string
 	SampleDistrictId  int
 	Cluster           *gocb.Cluster
-	ReadsBucket       *gocb.Bucket
-	WritesBucket      *gocb.Bucket
+	Reads             DocumentStore
+	Writes            DocumentStore
+	Faults            *faults.Injector
+	Workers           *workers.Group
+	connectMu         sync.Mutex
 }
 
 type ClientGroup struct {
@@ -36,6 +45,34 @@ type Location struct {
 	Latitude  float32 `json:"latitude" faker:"lat"`
 }
 
+// Address is a Fineract client address, with its type by name.
+type Address struct {
+	Type          string   `json:"type"`
+	Active        bool     `json:"active"`
+	Street        string   `json:"street"`
+	AddressLine1  string   `json:"addressLine1"`
+	AddressLine2  string   `json:"addressLine2"`
+	AddressLine3  string   `json:"addressLine3"`
+	City          string   `json:"city"`
+	StateProvince string   `json:"stateProvince"`
+	Country       string   `json:"country"`
+	PostalCode    string   `json:"postalCode"`
+	Location      Location `json:"location"`
+}
+
+// Identifier is a Fineract client identifier, with its document type by name.
+// Id is what a device sends back to update or remove it.
+type Identifier struct {
+	Id           int    `json:"id"`
+	DocumentType string `json:"documentType"`
+	DocumentKey  string `json:"documentKey"`
+	Description  string `json:"description"`
+	Status       string `json:"status"`
+}
+
+// nationalIdDocumentType is the document type whose key is the client's national id number.
+const nationalIdDocumentType = "National ID"
+
 type Contact struct {
 	PrimaryPhoneNumber   string      `json:"primaryPhoneNumber" faker:"e_164_phone_number"`
 	SecondaryPhoneNumber interface{} `json:"secondaryPhoneNumber" faker:"e_164_phone_number"`
@@ -67,23 +104,30 @@ type ApiRequest struct {
 }
 
 type Client struct {
-	Id               string      `json:"_id"`
-	AccountNo        string      `json:"accountNo" faker:"cc_number"`
-	Active           bool        `json:"active"`
-	ActivationDate   []string    `json:"activationDate" faker:"timestamp, slice_len=1"`
-	Firstname        string      `json:"firstname" faker:"first_name"`
-	Lastname         string      `json:"lastname" faker:"last_name"`
-	DisplayName      string      `json:"displayName" faker:"name"`
-	OfficeId         int         `json:"officeId"`
-	Dob              string      `json:"dob" faker:"date"`
-	Gender           string      `json:"gender" faker:"oneof: M, F"`
-	NationalIdNumber string      `json:"nationalIdNumber" faker:"cc_number"`
-	Location         Location    `json:"location"`
-	Contacts         Contact     `json:"contacts"`
-	Group            ClientGroup `json:"group"`
-	Channels         []string    `json:"channels"`
-	SyncTs           string      `json:"syncTs" faker:"date"`
-	Type             string      `json:"type" faker:"oneof: clients"`
+	Id         string `json:"_id"`
+	FineractId int    `json:"fineractId"`
+	AccountNo  string `json:"accountNo" faker:"cc_number"`
+	Active     bool   `json:"active"`
+	//ActivationDate was an array of strings before it became an ISO date, see MigrateActivationDates
+	ActivationDate   string       `json:"activationDate" faker:"date"`
+	Firstname        string       `json:"firstname" faker:"first_name"`
+	Lastname         string       `json:"lastname" faker:"last_name"`
+	DisplayName      string       `json:"displayName" faker:"name"`
+	OfficeId         int          `json:"officeId"`
+	Dob              string       `json:"dob" faker:"date"`
+	Gender           string       `json:"gender" faker:"oneof: M, F"`
+	ClientType       string       `json:"clientType"`
+	Classification   string       `json:"clientClassification"`
+	NationalIdNumber string       `json:"nationalIdNumber" faker:"cc_number"`
+	Identifiers      []Identifier `json:"identifiers"`
+	Location         Location     `json:"location"`
+	Addresses        []Address    `json:"addresses"`
+	Contacts         Contact      `json:"contacts"`
+	Group            ClientGroup  `json:"group"`
+	Channels         []string     `json:"channels"`
+	SyncTs           string       `json:"syncTs" faker:"date"`
+	ContentHash      string       `json:"contentHash,omitempty"`
+	Type             string       `json:"type" faker:"oneof: clients"`
 }
 
 type GroupConfigurations struct {
@@ -96,12 +140,13 @@ type Group struct {
 	AccountNo      string              `json:"accountNo" faker:"cc_number"`
 	Name           string              `json:"name" faker:"name"`
 	Active         bool                `json:"active"`
-	ActivationDate []string            `json:"activationDate" faker:"timestamp, slice_len=1"`
+	ActivationDate string              `json:"activationDate" faker:"date"`
 	OfficeId       int                 `json:"officeId"`
 	OfficeName     string              `json:"officeName" faker:"name"`
 	Channels       []string            `json:"channels"`
 	Configurations GroupConfigurations `json:"configurations"`
 	SyncTs         string              `json:"syncTs" faker:"date"`
+	ContentHash    string              `json:"contentHash,omitempty"`
 	Type           string              `json:"type" faker:"oneof: groups"`
 }
 
@@ -127,9 +172,20 @@ func NewService(
 	}
 }
 
+// NewServiceWithStores builds a Service on top of already opened stores,
+// skipping the Couchbase connection entirely.
+func NewServiceWithStores(reads DocumentStore, writes DocumentStore) *Service {
+	return &Service{
+		Reads:  reads,
+		Writes: writes,
+	}
+}
+
 func (s *Service) ensureConnection() error {
+	s.connectMu.Lock()
+	defer s.connectMu.Unlock()
 
-	if s.Cluster != nil && s.ReadsBucket != nil {
+	if s.Reads != nil && s.Writes != nil {
 		return nil
 	}
 
@@ -158,37 +214,159 @@ func (s *Service) ensureConnection() error {
 	log.Println("Successfully connected to Couchbase")
 
 	s.Cluster = cluster
-	s.ReadsBucket = readsBucket
-	s.WritesBucket = writesBucket
+	s.Reads = s.withFaults(NewBucketStore(cluster, readsBucket), "reads")
+	s.Writes = s.withFaults(NewBucketStore(cluster, writesBucket), "writes")
 
 	return nil
 }
 
+// Ping connects if needed and checks both stores.
+// A slow connection carries on in the background once ctx is done, the probe doesn't wait for it.
+func (s *Service) Ping(ctx context.Context) error {
+	connected := make(chan error, 1)
+	go func() {
+		connected <- s.ensureConnection()
+	}()
+
+	var err error
+	select {
+	case err = <-connected:
+	case <-ctx.Done():
+		return fmt.Errorf("connecting: %w", ctx.Err())
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = s.Reads.Ping(ctx)
+
+	if err != nil {
+		return fmt.Errorf("reads: %w", err)
+	}
+
+	err = s.Writes.Ping(ctx)
+
+	if err != nil {
+		return fmt.Errorf("writes: %w", err)
+	}
+
+	return nil
+}
+
+// UseFaults routes every store operation through the fault injector,
+// including stores opened later by the lazy Couchbase connection.
+func (s *Service) UseFaults(injector *faults.Injector) {
+	s.Faults = injector
+
+	if s.Reads != nil {
+		s.Reads = s.withFaults(s.Reads, "reads")
+	}
+	if s.Writes != nil {
+		s.Writes = s.withFaults(s.Writes, "writes")
+	}
+}
+
+func (s *Service) withFaults(store DocumentStore, bucket string) DocumentStore {
+	if s.Faults == nil {
+		return store
+	}
+	return NewFaultStore(store, bucket, s.Faults)
+}
+
+// ProcessApiRequest claims the API request and sends it to Fineract in the background.
+// The background slot is taken before claiming, so a draining server leaves the request untouched
+// and returns workers.ErrDraining.
 func (s *Service) ProcessApiRequest(id string, cliffService *cliff.Service) error {
-	err := s.ensureConnection()
+	claimed := make(chan ApiRequest, 1)
+
+	err := s.background("api request "+id, func(ctx context.Context) {
+		apiRequest, ok := <-claimed
+		if ok {
+			s.completeApiRequest(ctx, id, apiRequest, cliffService)
+		}
+	})
 
 	if err != nil {
-		log.Println(err)
 		return err
 	}
 
-	log.Println("Processing API request for", id)
-	wCol := s.WritesBucket.DefaultCollection()
-	//wCol := s.WritesBucket.DefaultCollection()
+	apiRequest, err := s.claimApiRequest(id)
 
-	results, err := wCol.Get(id, nil)
+	if err != nil {
+		close(claimed)
+		return err
+	}
+
+	claimed <- apiRequest
+	return nil
+}
+
+// background runs fn on the worker group when there is one, so shutdown waits for it.
+func (s *Service) background(name string, fn func(ctx context.Context)) error {
+	if s.Workers == nil {
+		go fn(context.Background())
+		return nil
+	}
+
+	return s.Workers.Go(name, fn)
+}
+
+// Close releases the stores and the Couchbase cluster connection.
+func (s *Service) Close() error {
+	s.connectMu.Lock()
+	defer s.connectMu.Unlock()
+
+	var firstErr error
+	for _, store := range []DocumentStore{s.Reads, s.Writes} {
+		if closer, ok := store.(io.Closer); ok {
+			err := closer.Close()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if s.Cluster != nil {
+		err := s.Cluster.Close(nil)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		log.Println("Closed Couchbase connection")
+	}
+
+	return firstErr
+}
+
+// ProcessApiRequestSync processes an API request like ProcessApiRequest but waits for Fineract,
+// returning the document as it was finally saved.
+func (s *Service) ProcessApiRequestSync(id string, cliffService *cliff.Service) (ApiRequest, error) {
+	apiRequest, err := s.claimApiRequest(id)
+
+	if err != nil {
+		return ApiRequest{}, err
+	}
+
+	return s.completeApiRequest(context.Background(), id, apiRequest, cliffService), nil
+}
+
+// claimApiRequest loads the API request and marks it as PROCESSING.
+func (s *Service) claimApiRequest(id string) (ApiRequest, error) {
+	err := s.ensureConnection()
 
 	if err != nil {
 		log.Println(err)
-		return err
+		return ApiRequest{}, err
 	}
 
+	log.Println("Processing API request for", id)
+
 	var apiRequest ApiRequest
-	err = results.Content(&apiRequest)
+	err = s.Writes.Get(id, &apiRequest)
 
 	if err != nil {
 		log.Println(err)
-		return err
+		return ApiRequest{}, err
 	}
 
 	log.Println("Found document with id", id)
@@ -200,138 +378,246 @@ func (s *Service) ProcessApiRequest(id string, cliffService *cliff.Service) erro
 		},
 	}
 
-	_, err = wCol.Upsert(id, apiRequest, nil)
+	err = s.Writes.Upsert(id, apiRequest)
 
 	if err != nil {
 		log.Println(err)
-		return err
+		return ApiRequest{}, err
 	}
 
 	log.Println("Updated Document State for ", id)
 
-	go func() {
-		log.Println("Started Processing Document", id)
+	return apiRequest, nil
+}
 
-		var parsedClientRequestBody shared.ParsedClientRequestBody
-		err1 := json.Unmarshal([]byte(apiRequest.RequestData), &parsedClientRequestBody)
+// completeApiRequest sends the request to Fineract and records the outcome on the document.
+// Once ctx is cancelled by a timed out drain nothing is sent and the request stays PROCESSING.
+func (s *Service) completeApiRequest(ctx context.Context, id string, apiRequest ApiRequest, cliffService *cliff.Service) ApiRequest {
+	if ctx.Err() != nil {
+		log.Println("Not sending", id, "to Fineract:", ctx.Err())
+		return apiRequest
+	}
 
-		apiRequest.ResponseStatusCode = 201
+	log.Println("Started Processing Document", id)
 
-		newStates := append(apiRequest.DocumentStates, DocumentState{
-			Time:   time.Now(),
-			Status: "PROCESSED",
-		})
-		apiRequest.DocumentStates = newStates
+	var parsedClientRequestBody shared.ParsedClientRequestBody
+	err1 := json.Unmarshal([]byte(apiRequest.RequestData), &parsedClientRequestBody)
 
-		if err1 != nil {
-			log.Println(err)
-			apiRequest.ResponseStatusCode = 400
-		}
+	apiRequest.ResponseStatusCode = 201
+
+	var resp shared.CreateClientResponse
+	var err2 error
+	var code int
+
+	//a body that doesn't parse never reaches Fineract
+	if err1 != nil {
+		log.Println(err1)
+		apiRequest.ResponseStatusCode = 400
+		apiRequest.ResponseData = err1.Error()
+	} else {
+		resp, err2, code = cliffService.UpsertClient(parsedClientRequestBody, apiRequest.Verb, apiRequest.ClientEmail)
+	}
+
+	if err2 != nil {
+		log.Println(err2)
+		apiRequest.ResponseStatusCode = code
+		apiRequest.ResponseData = err2.Error()
+	}
 
-		resp, err2, code := cliffService.UpsertClient(parsedClientRequestBody, apiRequest.Verb)
+	//a 503, e.g. Fineract's code tables not loaded yet, leaves the request pending,
+	//ResendPendingApiRequests sends it again after the next code value refresh
+	status := "PROCESSED"
+	if apiRequest.ResponseStatusCode == http.StatusServiceUnavailable {
+		status = "PENDING"
+	}
+	apiRequest.DocumentStates = append(apiRequest.DocumentStates, DocumentState{
+		Time:   time.Now(),
+		Status: status,
+	})
+
+	if err1 == nil && err2 == nil {
+		//write the server-confirmed client back to the reads bucket
+		client := s.writeBackClient(resp, cliffService)
 
-		if err2 != nil {
-			log.Println(err)
-			apiRequest.ResponseStatusCode = code
-			apiRequest.ResponseData = err2.Error()
+		//Fineract only returns the account number of a created client, an update takes the one read back
+		accountNo := resp.AccountNo
+		if accountNo == "" {
+			accountNo = client.AccountNo
 		}
 
-		if err1 == nil && err2 == nil {
-			clientUpdateResonse := ClientUpdateDto{
-				CreatedAt:     time.Now(),
-				UpdatedAt:     time.Now(),
-				AccountNumber: resp.AccountNo,
-			}
-			response, _ := json.Marshal(clientUpdateResonse)
-			apiRequest.ResponseData = string(response)
+		clientUpdateResonse := ClientUpdateDto{
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+			AccountNumber: accountNo,
 		}
+		response, _ := json.Marshal(clientUpdateResonse)
+		apiRequest.ResponseData = string(response)
+	}
 
-		_, err = wCol.Upsert(id, apiRequest, nil)
+	err := s.Writes.Upsert(id, apiRequest)
 
-		if err != nil {
-			log.Println(err)
-		}
+	if err != nil {
+		log.Println(err)
+	}
 
-		log.Println("Finished Processing Document", id)
-	}()
+	log.Println("Finished Processing Document", id)
 
-	return nil
+	return apiRequest
 }
 
+// SyncProgress receives the outcome of every record saved by an initialization.
+type SyncProgress interface {
+	AddTotal(n int)
+	Synced(id string)
+	Unchanged(id string)
+	Failed(id string, err error)
+}
+
+// syncCount is the SyncProgress of initializations nobody tracks, it only counts for the log.
+type syncCount struct {
+	total  int
+	synced int
+}
+
+func (c *syncCount) AddTotal(n int)              { c.total += n }
+func (c *syncCount) Synced(id string)            { c.synced++ }
+func (c *syncCount) Unchanged(id string)         {}
+func (c *syncCount) Failed(id string, err error) { log.Println("Couldn't save", id, err) }
+
 func (s *Service) SaveInitialClients(cliffClients []shared.ClientDTO) {
-	err := s.ensureConnection()
+	count := &syncCount{}
+	err := s.SaveClients(context.Background(), cliffClients, count)
 
 	if err != nil {
 		log.Println(err)
 		return
 	}
+	log.Println("Synced", count.synced, "clients", "Out of", count.total)
+}
 
-	var totalSynced int
+func (s *Service) SaveInitialGroups(cliffGroups []shared.GroupDTO) {
+	count := &syncCount{}
+	err := s.SaveGroups(context.Background(), cliffGroups, count)
+
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	log.Println("Synced", count.synced, "groups", "Out of", count.total)
+}
+
+// SaveClients upserts Fineract clients into the reads bucket, reporting each one to progress.
+// It stops with ctx.Err() when ctx is cancelled.
+func (s *Service) SaveClients(ctx context.Context, cliffClients []shared.ClientDTO, progress SyncProgress) error {
+	err := s.ensureConnection()
+
+	if err != nil {
+		return err
+	}
+
+	progress.AddTotal(len(cliffClients))
 
-	col := s.ReadsBucket.DefaultCollection()
 	for _, client := range cliffClients {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		cbClient := convertCliffClientToClient(client)
 
-		_, err = col.Upsert(cbClient.Id, cbClient, nil)
+		changed, err := s.upsertIfChanged(cbClient.Id, cbClient, cbClient.ContentHash)
 
-		if err != nil {
-			log.Println("Couldn't save client", err)
-			continue
+		switch {
+		case err != nil:
+			progress.Failed(cbClient.Id, err)
+		case changed:
+			progress.Synced(cbClient.Id)
+		default:
+			progress.Unchanged(cbClient.Id)
 		}
-		totalSynced += 1
 	}
-	log.Println("Synced", totalSynced, "clients", "Out of", len(cliffClients))
-	return
+	return nil
 }
 
-func (s Service) SaveInitialGroups(cliffGroups []shared.GroupDTO) {
+// SaveGroups upserts Fineract groups into the reads bucket, like SaveClients.
+func (s *Service) SaveGroups(ctx context.Context, cliffGroups []shared.GroupDTO, progress SyncProgress) error {
 	err := s.ensureConnection()
 
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
-	var totalSynced int
+	progress.AddTotal(len(cliffGroups))
 
-	col := s.ReadsBucket.DefaultCollection()
 	for _, group := range cliffGroups {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		toGroup := convertCliffGroupToGroup(group)
 
-		_, err = col.Upsert(toGroup.Id, toGroup, nil)
+		changed, err := s.upsertIfChanged(toGroup.Id, toGroup, toGroup.ContentHash)
 
-		if err != nil {
-			log.Println("Couldn't save group", err)
-			continue
+		switch {
+		case err != nil:
+			progress.Failed(toGroup.Id, err)
+		case changed:
+			progress.Synced(toGroup.Id)
+		default:
+			progress.Unchanged(toGroup.Id)
 		}
-		totalSynced += 1
 	}
-	log.Println("Synced", totalSynced, "groups", "Out of", len(cliffGroups))
-	return
+	return nil
 }
 
-func (s Service) UpdateClientFromWebhook(cliffClient shared.ClientDTO) error {
+func (s *Service) UpdateClientFromWebhook(cliffClient shared.ClientDTO) error {
 	err := s.ensureConnection()
 
 	if err != nil {
 		return err
 	}
 
-	col := s.ReadsBucket.DefaultCollection()
 	cbClient := convertCliffClientToClient(cliffClient)
 
-	_, err = col.Upsert(cbClient.Id, cbClient, nil)
-	log.Println("Saved client", cbClient.Id)
+	changed, err := s.upsertIfChanged(cbClient.Id, cbClient, cbClient.ContentHash)
 
 	if err != nil {
 		return err
 	}
 
+	if !changed {
+		log.Println("Client", cbClient.Id, "unchanged, skipped")
+		return nil
+	}
+
 	log.Println("Updated client", cbClient.Id)
 
 	return nil
 }
 
+// writeBackClient fetches a client Fineract just created or updated and upserts its reads document,
+// so the officer's device gets the server-confirmed record on the next replication.
+// The client is returned, empty when it couldn't be fetched.
+func (s *Service) writeBackClient(resp shared.CreateClientResponse, cliffService *cliff.Service) shared.ClientDTO {
+	clientId := resp.ClientId
+	if clientId == 0 {
+		clientId = resp.ResourceId
+	}
+
+	cliffClient, err := cliffService.GetClientById(strconv.Itoa(clientId))
+
+	if err != nil {
+		log.Println("Couldn't fetch client", clientId, err)
+		return shared.ClientDTO{}
+	}
+
+	err = s.UpdateClientFromWebhook(cliffClient)
+
+	if err != nil {
+		log.Println("Couldn't write back client", clientId, err)
+	}
+	return cliffClient
+}
+
 func convertCliffGroupToGroup(cliffGroup shared.GroupDTO) Group {
 	cliffGropuIdStr := strconv.Itoa(cliffGroup.Id)
 
@@ -340,17 +626,12 @@ func convertCliffGroupToGroup(cliffGroup shared.GroupDTO) Group {
 		MaxClientsInGroup: cliffGroup.Configurations.MaxClientsInGroup,
 	}
 
-	var strActivationDate []string
-	for _, date := range cliffGroup.ActivationDate {
-		strActivationDate = append(strActivationDate, strconv.Itoa(date))
-	}
-
-	return Group{
+	group := Group{
 		Id:             cliffGropuIdStr,
 		Name:           cliffGroup.Name,
 		AccountNo:      cliffGroup.AccountNo,
 		Active:         cliffGroup.Active,
-		ActivationDate: strActivationDate,
+		ActivationDate: dates.FineractToISO(cliffGroup.ActivationDate),
 		OfficeId:       cliffGroup.OfficeId,
 		OfficeName:     cliffGroup.OfficeName,
 		Channels:       []string{"clients_" + strconv.Itoa(cliffGroup.OfficeId)},
@@ -358,118 +639,118 @@ func convertCliffGroupToGroup(cliffGroup shared.GroupDTO) Group {
 		SyncTs:         time.Now().Format("2006-01-02 15:04:05"),
 		Type:           "groups",
 	}
+	group.ContentHash = contentHash(group)
+	return group
 }
 
 func convertCliffClientToClient(client shared.ClientDTO) Client {
-	var strActivationDate []string
-	for _, date := range client.ActivationDate {
-		strActivationDate = append(strActivationDate, strconv.Itoa(date))
+	contacts := Contact{
+		PrimaryPhoneNumber: client.MobileNo,
 	}
 
-	//TODO: Fix this
-	//dob := time.Date(client.DateOfBirth[0], time.Month(client.DateOfBirth[1]), client.DateOfBirth[2], 0, 0, 0, 0, time.UTC)
-	//to format 1981-01-01T00:00:00.000Z
-	dobStr := "1991-04-01" //dob.Format(time.RFC3339)
+	addresses := []Address{}
+	var location Location
+	primary := false
+	for _, cliffAddress := range client.Addresses {
+		address := convertCliffAddressToAddress(cliffAddress)
+		addresses = append(addresses, address)
+
+		//the first active address is the one shown on the client
+		if address.Active && !primary {
+			primary = true
+			contacts.AddressLine1 = address.AddressLine1
+			contacts.AddressLine2 = address.AddressLine2
+			location = address.Location
+		}
+	}
 
-	contacts := Contact{
-		PrimaryPhoneNumber: client.MobileNo,
+	identifiers := []Identifier{}
+	nationalIdNumber := client.ExternalId
+	for _, cliffIdentifier := range client.Identifiers {
+		identifier := convertCliffIdentifierToIdentifier(cliffIdentifier)
+		identifiers = append(identifiers, identifier)
+
+		if identifier.Status == "active" && strings.EqualFold(identifier.DocumentType, nationalIdDocumentType) {
+			nationalIdNumber = identifier.DocumentKey
+		}
 	}
 
 	cbClient := Client{
 		Id:               "clients_" + client.AccountNo,
+		FineractId:       client.Id,
 		AccountNo:        client.AccountNo,
 		Active:           client.Active,
-		ActivationDate:   strActivationDate,
+		ActivationDate:   dates.FineractToISO(client.ActivationDate),
 		Firstname:        client.Firstname,
 		Lastname:         client.Lastname,
 		DisplayName:      client.DisplayName,
 		OfficeId:         client.OfficeId,
-		Dob:              dobStr,
+		Dob:              dates.FineractToISO(client.DateOfBirth),
 		Gender:           client.Gender.Name,
-		NationalIdNumber: client.ExternalId,
+		ClientType:       client.ClientType.Name,
+		Classification:   client.ClientClassification.Name,
+		NationalIdNumber: nationalIdNumber,
+		Identifiers:      identifiers,
+		Location:         location,
+		Addresses:        addresses,
 		Contacts:         contacts,
 		Channels:         []string{"clients_" + strconv.Itoa(client.OfficeId)},
 		SyncTs:           time.Now().Format("2006-01-02 15:04:05"),
 		Type:             "clients",
 	}
+	cbClient.ContentHash = contentHash(cbClient)
 	return cbClient
 }
 
-func (s *Service) PublishDocs(channels []string) (uint, error) {
-	err := s.ensureConnection()
-
-	if err != nil {
-		log.Println(err)
-		return 0, err
+func convertCliffIdentifierToIdentifier(cliffIdentifier shared.IdentifierDTO) Identifier {
+	//Fineract returns statuses like "clientIdentifierStatusType.active"
+	status := cliffIdentifier.Status
+	if i := strings.LastIndex(status, "."); i >= 0 {
+		status = status[i+1:]
 	}
 
-	//const
-	const numDocs = 20
-	successfulDocs := uint(0)
-
-	//TODO: make this concurrent
-	for i := 0; i < numDocs; i++ {
-		col := s.ReadsBucket.DefaultCollection()
-		client := Client{}
-		group := Group{}
-
-		err = faker.FakeData(&client)
-		groupError := faker.FakeData(&group)
-
-		if err != nil {
-			message := fmt.Sprintf("%s on document # %s", err, string(rune(i)))
-			log.Println(message)
-			continue
-		}
+	return Identifier{
+		Id:           cliffIdentifier.Id,
+		DocumentType: cliffIdentifier.DocumentType.Name,
+		DocumentKey:  cliffIdentifier.DocumentKey,
+		Description:  cliffIdentifier.Description,
+		Status:       strings.ToLower(status),
+	}
+}
 
-		if groupError != nil {
-			message := fmt.Sprintf("%s on document # %s", err, string(rune(i)))
-			log.Println(message)
-			continue
-		}
+func convertCliffAddressToAddress(cliffAddress shared.AddressDTO) Address {
+	return Address{
+		Type:          cliffAddress.AddressType,
+		Active:        cliffAddress.IsActive,
+		Street:        cliffAddress.Street,
+		AddressLine1:  cliffAddress.AddressLine1,
+		AddressLine2:  cliffAddress.AddressLine2,
+		AddressLine3:  cliffAddress.AddressLine3,
+		City:          cliffAddress.City,
+		StateProvince: cliffAddress.StateName,
+		Country:       cliffAddress.CountryName,
+		PostalCode:    cliffAddress.PostalCode,
+		Location: Location{
+			Latitude:  float32(cliffAddress.Latitude),
+			Longitude: float32(cliffAddress.Longitude),
+		},
+	}
+}
 
-		//TODO: get channel with underscore in it...
-		//find channel with "clients" in it
-		clientChannel, groupChannel := "", ""
-		for _, channel := range channels {
-			if strings.Contains(channel, "clients") {
-				clientChannel = channel
-			}
-			if strings.Contains(channel, "groups") {
-				groupChannel = channel
-			}
-		}
+// Store returns the document store behind the "reads" or "writes" bucket.
+func (s *Service) Store(bucket string) (DocumentStore, error) {
+	err := s.ensureConnection()
 
-		client.Channels = []string{clientChannel}
-		group.Channels = []string{groupChannel}
-		now := time.Now().Format("2006-01-02 15:04:05")
-		client.SyncTs = now
-		group.SyncTs = now
-		client.Id = "clients_" + client.AccountNo
-		group.Id = "groups_" + group.AccountNo
-		//unix timestamp
-		client.ActivationDate = []string{fmt.Sprintf("%d", time.Now().Unix())}
-
-		_, err = col.Upsert(client.Id, client, nil)
-		_, groupError = col.Upsert(group.Id, group, nil)
-
-		if err != nil {
-			message := fmt.Sprintf("%s on document # %s", err, string(rune(i)))
-			log.Println(message)
-			continue
-		} else {
-			log.Println("Successfully published Client document ID", client.Id)
-			successfulDocs += 1
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		if groupError != nil {
-			message := fmt.Sprintf("%s on document # %s", err, string(rune(i)))
-			log.Println(message)
-			continue
-		} else {
-			log.Println("Successfully published Group document ID", group.Id)
-			successfulDocs += 1
-		}
+	switch bucket {
+	case "reads":
+		return s.Reads, nil
+	case "writes":
+		return s.Writes, nil
+	default:
+		return nil, fmt.Errorf("unknown bucket %q", bucket)
 	}
-	return successfulDocs, nil
 }
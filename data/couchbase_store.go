@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/couchbase/gocb/v2"
+	"time"
+)
+
+type BucketStore struct {
+	Cluster    *gocb.Cluster
+	Bucket     *gocb.Bucket
+	Collection *gocb.Collection
+}
+
+func NewBucketStore(cluster *gocb.Cluster, bucket *gocb.Bucket) *BucketStore {
+	return &BucketStore{
+		Cluster:    cluster,
+		Bucket:     bucket,
+		Collection: bucket.DefaultCollection(),
+	}
+}
+
+func (b *BucketStore) Get(id string, valuePtr interface{}) error {
+	result, err := b.Collection.Get(id, nil)
+
+	if errors.Is(err, gocb.ErrDocumentNotFound) {
+		return ErrDocumentNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return result.Content(valuePtr)
+}
+
+func (b *BucketStore) Upsert(id string, value interface{}) error {
+	_, err := b.Collection.Upsert(id, value, nil)
+	return err
+}
+
+func (b *BucketStore) UpsertBatch(items []BatchItem) []error {
+	ops := make([]gocb.BulkOp, len(items))
+	for i, item := range items {
+		ops[i] = &gocb.UpsertOp{ID: item.Id, Value: item.Value}
+	}
+
+	errs := make([]error, len(items))
+	err := b.Collection.Do(ops, nil)
+
+	for i, op := range ops {
+		errs[i] = op.(*gocb.UpsertOp).Err
+		if errs[i] == nil {
+			errs[i] = err
+		}
+	}
+
+	return errs
+}
+
+func (b *BucketStore) Remove(id string) error {
+	_, err := b.Collection.Remove(id, nil)
+
+	if errors.Is(err, gocb.ErrDocumentNotFound) {
+		return ErrDocumentNotFound
+	}
+
+	return err
+}
+
+func (b *BucketStore) Ping(ctx context.Context) error {
+	result, err := b.Bucket.Ping(&gocb.PingOptions{
+		ServiceTypes: []gocb.ServiceType{gocb.ServiceTypeKeyValue},
+		Timeout:      2 * time.Second,
+		Context:      ctx,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, reports := range result.Services {
+		for _, report := range reports {
+			if report.State != gocb.PingStateOk {
+				return fmt.Errorf("bucket %s endpoint %s: %s", b.Bucket.Name(), report.Remote, report.Error)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Query uses an id range rather than LIKE, which would read the _ of prefixes like clients_ as a wildcard.
+func (b *BucketStore) Query(prefix string) ([]Document, error) {
+	statement := fmt.Sprintf("SELECT META(d).id AS id, d AS doc FROM `%s` AS d", b.Bucket.Name())
+	parameters := map[string]interface{}{}
+
+	if prefix != "" {
+		statement += " WHERE META(d).id >= $prefix"
+		parameters["prefix"] = prefix
+
+		if end, ok := prefixEnd(prefix); ok {
+			statement += " AND META(d).id < $end"
+			parameters["end"] = end
+		}
+	}
+
+	return b.query(statement, parameters)
+}
+
+// QueryOffice filters on type and officeId in the query, so only the office's documents are read.
+// An index on (type, officeId) spares the bucket scan.
+func (b *BucketStore) QueryOffice(documentType string, officeId int) ([]Document, error) {
+	statement := fmt.Sprintf("SELECT META(d).id AS id, d AS doc FROM `%s` AS d WHERE d.type = $type AND d.officeId = $officeId", b.Bucket.Name())
+
+	return b.query(statement, map[string]interface{}{"type": documentType, "officeId": officeId})
+}
+
+// InsertWithExpiry creates the document unless it exists, false when it does.
+func (b *BucketStore) InsertWithExpiry(id string, value interface{}, expiry time.Duration) (bool, error) {
+	_, err := b.Collection.Insert(id, value, &gocb.InsertOptions{Expiry: expiry})
+
+	if errors.Is(err, gocb.ErrDocumentExists) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (b *BucketStore) query(statement string, parameters map[string]interface{}) ([]Document, error) {
+	results, err := b.Cluster.Query(statement, &gocb.QueryOptions{
+		NamedParameters: parameters,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []Document
+	for results.Next() {
+		var document Document
+		err = results.Row(&document)
+
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+
+	return documents, results.Err()
+}
+
+// prefixEnd is the smallest string after every string starting with prefix,
+// false when there is none because prefix is all 0xff bytes.
+func prefixEnd(prefix string) (string, bool) {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1]), true
+		}
+	}
+	return "", false
+}
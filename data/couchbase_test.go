@@ -0,0 +1,87 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"mock-server/cliff"
+	"mock-server/fakegen"
+	"mock-server/mockcliff"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+const fineractPath = "/fineract-provider/api/v1"
+
+// newTestServices returns a Service on memory stores and a cliff.Service pointed at a mock Fineract.
+func newTestServices(t *testing.T) (*Service, *cliff.Service, *mockcliff.Server) {
+	server := mockcliff.NewServer()
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	cliffService := cliff.NewCliffService(httpServer.URL, "token", "1", fineractPath+"/clients", fineractPath+"/groups", fineractPath+"/offices", fineractPath+"/audits", fineractPath+"/codes", fineractPath+"/clients", fineractPath+"/clients")
+	cliffService.AddressTypeId = 1
+	cliffService.Codes = cliff.NewCodeCache(cliffService)
+	err := cliffService.Codes.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Service{Reads: NewMemoryStore(), Writes: NewMemoryStore()}, cliffService, server
+}
+
+func TestCompleteApiRequest(t *testing.T) {
+	service, cliffService, server := newTestServices(t)
+
+	generator, err := fakegen.New(1, fakegen.DefaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := server.SeedClients(generator, 1, 1, true)[0]
+
+	tests := []struct {
+		name          string
+		requestData   string
+		wantStatus    int
+		wantAccountNo string
+	}{
+		{
+			name:        "create",
+			requestData: `{"clientBio":{"firstname":"Ada","lastname":"Lovelace","active":true,"legalForm":"PERSON"}}`,
+			wantStatus:  201,
+		},
+		{
+			name:          "update takes the account number read back",
+			requestData:   `{"fineractClientId":` + strconv.Itoa(existing.Id) + `,"clientBio":{"firstname":"Grace"}}`,
+			wantStatus:    201,
+			wantAccountNo: existing.AccountNo,
+		},
+		{
+			name:        "body that doesn't parse",
+			requestData: `{"clientBio":`,
+			wantStatus:  400,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apiRequest := service.completeApiRequest(context.Background(), "api_request_"+test.name, ApiRequest{Verb: "POST", RequestData: test.requestData}, cliffService)
+
+			if apiRequest.ResponseStatusCode != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", apiRequest.ResponseStatusCode, test.wantStatus, apiRequest.ResponseData)
+			}
+			if test.wantStatus != 201 {
+				return
+			}
+
+			var response ClientUpdateDto
+			err := json.Unmarshal([]byte(apiRequest.ResponseData), &response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.AccountNumber == "" || (test.wantAccountNo != "" && response.AccountNumber != test.wantAccountNo) {
+				t.Errorf("accountNumber = %q, want %q", response.AccountNumber, test.wantAccountNo)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mock-server/cliff"
+	"mock-server/shared"
+	"strconv"
+	"time"
+)
+
+// checkpointOverlap is re-read on every delta sync so clock skew with Fineract
+// doesn't lose changes, the content compare makes the overlap cheap.
+const checkpointOverlap = time.Minute
+
+// SyncCheckpoint records up to when an office was synced from Fineract.
+// It has no channels so it never replicates to devices.
+type SyncCheckpoint struct {
+	Id       string    `json:"_id"`
+	OfficeId string    `json:"officeId"`
+	Entity   string    `json:"entity"`
+	Since    time.Time `json:"since"`
+	Type     string    `json:"type"`
+}
+
+func checkpointId(entity string, officeId string) string {
+	return "checkpoints_" + entity + "_" + officeId
+}
+
+// SyncClientsDelta syncs the clients of an office changed in Fineract since the last successful
+// delta sync, found through the audit trail, and only upserts the ones whose content changed.
+// The first run has no checkpoint and compares every client of the office.
+// Deleted clients are left to reconciliation.
+func (s *Service) SyncClientsDelta(ctx context.Context, officeId string, cliffService *cliff.Service, progress SyncProgress) error {
+	err := s.ensureConnection()
+
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	failed := false
+
+	var checkpoint SyncCheckpoint
+	err = s.Reads.Get(checkpointId("clients", officeId), &checkpoint)
+
+	var cliffClients []shared.ClientDTO
+
+	switch {
+	case errors.Is(err, ErrDocumentNotFound):
+		var detailsFailed []cliff.ClientDetailsError
+		cliffClients, detailsFailed, err = cliffService.GetOfficeClients(ctx, officeId)
+
+		if err != nil {
+			return fmt.Errorf("fineract: %w", err)
+		}
+		progress.AddTotal(len(cliffClients) + len(detailsFailed))
+
+		for _, failure := range detailsFailed {
+			progress.Failed("clients_"+failure.Client.AccountNo, failure.Err)
+			failed = true
+		}
+	case err != nil:
+		return err
+	default:
+		audits, err := cliffService.GetClientAudits(officeId, checkpoint.Since)
+
+		if err != nil {
+			return fmt.Errorf("fineract audits: %w", err)
+		}
+
+		transfers, err := cliffService.GetClientTransfers(checkpoint.Since)
+
+		if err != nil {
+			return fmt.Errorf("fineract transfers: %w", err)
+		}
+
+		clientIds := changedClientIds(audits)
+		progress.AddTotal(len(clientIds))
+
+		audited := map[int]bool{}
+		for _, clientId := range clientIds {
+			audited[clientId] = true
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			cliffClient, err := cliffService.GetClientById(strconv.Itoa(clientId))
+
+			if err != nil {
+				progress.Failed("client "+strconv.Itoa(clientId), err)
+				failed = true
+				continue
+			}
+			cliffClients = append(cliffClients, cliffClient)
+		}
+
+		//a client transferred in was audited under the office it left, only the ones now here are kept
+		for _, clientId := range changedClientIds(transfers) {
+			if audited[clientId] {
+				continue
+			}
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			cliffClient, err := cliffService.GetClientById(strconv.Itoa(clientId))
+
+			if err != nil {
+				progress.AddTotal(1)
+				progress.Failed("client "+strconv.Itoa(clientId), err)
+				failed = true
+				continue
+			}
+
+			if strconv.Itoa(cliffClient.OfficeId) == officeId {
+				progress.AddTotal(1)
+				cliffClients = append(cliffClients, cliffClient)
+			}
+		}
+	}
+
+	for _, cliffClient := range cliffClients {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		changed, id, err := s.upsertClientIfChanged(cliffClient)
+
+		switch {
+		case err != nil:
+			progress.Failed(id, err)
+			failed = true
+		case changed:
+			progress.Synced(id)
+		default:
+			progress.Unchanged(id)
+		}
+	}
+
+	//a failed record is retried by the next run
+	if failed {
+		return nil
+	}
+
+	return s.Reads.Upsert(checkpointId("clients", officeId), SyncCheckpoint{
+		Id:       checkpointId("clients", officeId),
+		OfficeId: officeId,
+		Entity:   "clients",
+		Since:    started.Add(-checkpointOverlap),
+		Type:     "checkpoints",
+	})
+}
+
+// changedClientIds lists each audited client once, skipping deleted ones.
+// Deleting an identifier or address only changes its client.
+func changedClientIds(audits []shared.AuditDTO) []int {
+	deleted := map[int]bool{}
+	for _, audit := range audits {
+		if audit.ActionName == "DELETE" && audit.EntityName == "CLIENT" {
+			deleted[audit.ClientId] = true
+		}
+	}
+
+	seen := map[int]bool{}
+	var clientIds []int
+	for _, audit := range audits {
+		if seen[audit.ClientId] || deleted[audit.ClientId] || audit.ClientId == 0 {
+			continue
+		}
+		seen[audit.ClientId] = true
+		clientIds = append(clientIds, audit.ClientId)
+	}
+	return clientIds
+}
+
+// upsertClientIfChanged upserts the reads document of a Fineract client unless
+// the stored one already has the same content hash.
+func (s *Service) upsertClientIfChanged(cliffClient shared.ClientDTO) (bool, string, error) {
+	cbClient := convertCliffClientToClient(cliffClient)
+
+	changed, err := s.upsertIfChanged(cbClient.Id, cbClient, cbClient.ContentHash)
+	return changed, cbClient.Id, err
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"mock-server/dates"
+	"mock-server/fakegen"
+)
+
+func fakeClient(generator *fakegen.Generator) Client {
+	firstname, gender := generator.Person()
+	lastname := generator.LastName()
+	latitude, longitude := generator.Coordinates()
+	now := generator.Reference
+	dob := generator.Date(now.AddDate(-70, 0, 0), now.AddDate(-18, 0, 0))
+	accountNo := generator.AccountNo()
+	nationalId := generator.NationalId()
+
+	return Client{
+		Id:               "clients_" + accountNo,
+		AccountNo:        accountNo,
+		Active:           true,
+		ActivationDate:   dates.ISO(now),
+		Firstname:        firstname,
+		Lastname:         lastname,
+		DisplayName:      firstname + " " + lastname,
+		Dob:              dates.ISO(dob),
+		Gender:           gender,
+		NationalIdNumber: nationalId,
+		Identifiers: []Identifier{{
+			DocumentType: nationalIdDocumentType,
+			DocumentKey:  nationalId,
+			Status:       "active",
+		}},
+		Location: Location{Latitude: latitude, Longitude: longitude},
+		Addresses: []Address{{
+			Type:     "Home",
+			Active:   true,
+			City:     generator.Place(),
+			Location: Location{Latitude: latitude, Longitude: longitude},
+		}},
+		Contacts: Contact{PrimaryPhoneNumber: generator.PhoneNumber()},
+		SyncTs:   now.Format("2006-01-02 15:04:05"),
+		Type:     "clients",
+	}
+}
+
+func fakeGroup(generator *fakegen.Generator) Group {
+	now := generator.Reference
+	accountNo := generator.AccountNo()
+
+	return Group{
+		Id:             "groups_" + accountNo,
+		AccountNo:      accountNo,
+		Name:           generator.LastName() + " Group",
+		Active:         true,
+		ActivationDate: dates.ISO(now),
+		OfficeName:     generator.Place(),
+		Configurations: GroupConfigurations{MinClientsInGroup: 0, MaxClientsInGroup: 30},
+		SyncTs:         now.Format("2006-01-02 15:04:05"),
+		Type:           "groups",
+	}
+}
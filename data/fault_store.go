@@ -0,0 +1,147 @@
+package data
+
+import (
+	"encoding/json"
+	"io"
+	"mock-server/faults"
+	"time"
+)
+
+// FaultStore applies store fault rules before delegating to the wrapped store.
+type FaultStore struct {
+	DocumentStore
+	Bucket   string
+	Injector *faults.Injector
+}
+
+func NewFaultStore(store DocumentStore, bucket string, injector *faults.Injector) *FaultStore {
+	return &FaultStore{
+		DocumentStore: store,
+		Bucket:        bucket,
+		Injector:      injector,
+	}
+}
+
+func (f *FaultStore) inject(operation string, id string) (faults.Rule, bool, error) {
+	rule, ok := f.Injector.Match(faults.TargetStore, f.Bucket, operation, id)
+	if !ok {
+		return rule, false, nil
+	}
+
+	rule.Delay()
+
+	if rule.Timeout {
+		return rule, true, faults.ErrTimeout
+	}
+	return rule, true, nil
+}
+
+func (f *FaultStore) Get(id string, valuePtr interface{}) error {
+	rule, ok, err := f.inject("get", id)
+	if err != nil {
+		return err
+	}
+
+	if ok && rule.MalformedJSON {
+		return json.Unmarshal([]byte(`{"malformed":`), valuePtr)
+	}
+
+	return f.DocumentStore.Get(id, valuePtr)
+}
+
+func (f *FaultStore) Upsert(id string, value interface{}) error {
+	rule, ok, err := f.inject("upsert", id)
+	if err != nil {
+		return err
+	}
+
+	if ok && rule.DropWrite {
+		return nil
+	}
+
+	return f.DocumentStore.Upsert(id, value)
+}
+
+// UpsertBatch applies the upsert rules per item and batches the rest,
+// so the wrapped store keeps its one round trip.
+func (f *FaultStore) UpsertBatch(items []BatchItem) []error {
+	errs := make([]error, len(items))
+	var pending []BatchItem
+	var positions []int
+
+	for i, item := range items {
+		rule, ok, err := f.inject("upsert", item.Id)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		if ok && rule.DropWrite {
+			continue
+		}
+
+		pending = append(pending, item)
+		positions = append(positions, i)
+	}
+
+	if len(pending) == 0 {
+		return errs
+	}
+
+	for i, err := range UpsertBatch(f.DocumentStore, pending) {
+		errs[positions[i]] = err
+	}
+	return errs
+}
+
+func (f *FaultStore) Remove(id string) error {
+	rule, ok, err := f.inject("remove", id)
+	if err != nil {
+		return err
+	}
+
+	if ok && rule.DropWrite {
+		return nil
+	}
+
+	return f.DocumentStore.Remove(id)
+}
+
+func (f *FaultStore) Query(prefix string) ([]Document, error) {
+	_, _, err := f.inject("query", prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.DocumentStore.Query(prefix)
+}
+
+func (f *FaultStore) QueryOffice(documentType string, officeId int) ([]Document, error) {
+	_, _, err := f.inject("query", "")
+	if err != nil {
+		return nil, err
+	}
+
+	return QueryOffice(f.DocumentStore, documentType, officeId)
+}
+
+func (f *FaultStore) InsertWithExpiry(id string, value interface{}, expiry time.Duration) (bool, error) {
+	rule, ok, err := f.inject("upsert", id)
+	if err != nil {
+		return false, err
+	}
+
+	if ok && rule.DropWrite {
+		return true, nil
+	}
+
+	return InsertWithExpiry(f.DocumentStore, id, value, expiry)
+}
+
+// Close forwards to the wrapped store when it holds resources, e.g. a FileStore.
+func (f *FaultStore) Close() error {
+	if closer, ok := f.DocumentStore.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"errors"
+	"mock-server/faults"
+	"testing"
+)
+
+func TestFaultStoreUpsertBatch(t *testing.T) {
+	injector := faults.NewInjector()
+	for _, rule := range []faults.Rule{
+		{Target: faults.TargetStore, Operation: "upsert", Pattern: "dropped_*", DropWrite: true},
+		{Target: faults.TargetStore, Operation: "upsert", Pattern: "slow_*", Timeout: true},
+		{Target: faults.TargetStore, Bucket: "reads", Operation: "upsert", Pattern: "writes_only_*", Timeout: true},
+	} {
+		_, err := injector.Add(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	memory := NewMemoryStore()
+	store := NewFaultStore(memory, "writes", injector)
+
+	items := []BatchItem{
+		{Id: "kept_1", Value: 1},
+		{Id: "dropped_1", Value: 2},
+		{Id: "slow_1", Value: 3},
+		{Id: "writes_only_1", Value: 4},
+	}
+	tests := []struct {
+		id      string
+		wantErr error
+		stored  bool
+	}{
+		{id: "kept_1", stored: true},
+		{id: "dropped_1"},
+		{id: "slow_1", wantErr: faults.ErrTimeout},
+		{id: "writes_only_1", stored: true},
+	}
+
+	errs := UpsertBatch(store, items)
+
+	for i, test := range tests {
+		if !errors.Is(errs[i], test.wantErr) {
+			t.Errorf("%s error = %v, want %v", test.id, errs[i], test.wantErr)
+		}
+
+		var value int
+		err := memory.Get(test.id, &value)
+		if stored := err == nil; stored != test.stored {
+			t.Errorf("%s stored = %v, want %v", test.id, stored, test.stored)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore is a MemoryStore that appends every mutation to a JSON-lines file,
+// so a laptop demo keeps its data across restarts. The log is compacted on open.
+type FileStore struct {
+	*MemoryStore
+	Path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+type fileStoreEntry struct {
+	Op      string          `json:"op"`
+	Id      string          `json:"id"`
+	Content json.RawMessage `json:"doc,omitempty"`
+}
+
+func OpenFileStore(dir string, name string) (*FileStore, error) {
+	err := os.MkdirAll(dir, 0755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	store := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		Path:        filepath.Join(dir, name+".jsonl"),
+	}
+
+	err = store.replay()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.compact()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (f *FileStore) replay() error {
+	file, err := os.Open(f.Path)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := reader.ReadBytes('\n')
+
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			var entry fileStoreEntry
+			err = json.Unmarshal(line, &entry)
+
+			if err != nil {
+				_, peekErr := reader.Peek(1)
+
+				//a crash mid-append leaves a partial last record, compaction drops it
+				if peekErr == io.EOF {
+					log.Println("Dropping partial last record of", f.Path, "at line", lineNo, err)
+					return nil
+				}
+				return fmt.Errorf("%s line %d: %w", f.Path, lineNo, err)
+			}
+
+			switch entry.Op {
+			case "upsert":
+				f.MemoryStore.docs[entry.Id] = entry.Content
+			case "remove":
+				delete(f.MemoryStore.docs, entry.Id)
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// compact rewrites the log with one upsert per live document and keeps it open for appending
+func (f *FileStore) compact() error {
+	tmpPath := f.Path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+
+	if err != nil {
+		return err
+	}
+
+	documents, _ := f.MemoryStore.Query("")
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+
+	for _, document := range documents {
+		err = encoder.Encode(fileStoreEntry{Op: "upsert", Id: document.Id, Content: document.Content})
+
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	err = writer.Flush()
+
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	//the log is only replaced once the compacted copy is on disk, a crash before leaves the old one
+	err = tmp.Sync()
+
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, f.Path)
+
+	if err != nil {
+		return err
+	}
+
+	f.file, err = os.OpenFile(f.Path, os.O_APPEND|os.O_WRONLY, 0644)
+	return err
+}
+
+func (f *FileStore) append(entry fileStoreEntry) error {
+	line, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = f.file.Write(append(line, '\n'))
+
+	if err != nil {
+		return err
+	}
+
+	return f.file.Sync()
+}
+
+func (f *FileStore) Upsert(id string, value interface{}) error {
+	content, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err = f.append(fileStoreEntry{Op: "upsert", Id: id, Content: content})
+
+	if err != nil {
+		return err
+	}
+
+	return f.MemoryStore.Upsert(id, json.RawMessage(content))
+}
+
+// Remove logs the removal before applying it, like Upsert, so a failed append leaves the document in place.
+func (f *FileStore) Remove(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.MemoryStore.mu.RLock()
+	_, ok := f.MemoryStore.docs[id]
+	f.MemoryStore.mu.RUnlock()
+
+	if !ok {
+		return ErrDocumentNotFound
+	}
+
+	err := f.append(fileStoreEntry{Op: "remove", Id: id})
+
+	if err != nil {
+		return err
+	}
+
+	return f.MemoryStore.Remove(id)
+}
+
+func (f *FileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
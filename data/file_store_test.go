@@ -0,0 +1,105 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFileStoreReplay(t *testing.T) {
+	tests := []struct {
+		name   string
+		apply  func(store *FileStore) error
+		wantId map[string]bool
+	}{
+		{
+			name:   "upserts",
+			apply:  func(store *FileStore) error { return store.Upsert("b", map[string]int{"v": 2}) },
+			wantId: map[string]bool{"a": true, "b": true},
+		},
+		{
+			name:   "remove",
+			apply:  func(store *FileStore) error { return store.Remove("a") },
+			wantId: map[string]bool{},
+		},
+		{
+			name: "remove then upsert again",
+			apply: func(store *FileStore) error {
+				err := store.Remove("a")
+				if err != nil {
+					return err
+				}
+				return store.Upsert("a", map[string]int{"v": 3})
+			},
+			wantId: map[string]bool{"a": true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			store, err := OpenFileStore(dir, "writes")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.Upsert("a", map[string]int{"v": 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = test.apply(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store.Close()
+
+			reopened, err := OpenFileStore(dir, "writes")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+
+			documents, err := reopened.Query("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(documents) != len(test.wantId) {
+				t.Fatalf("documents = %v, want %v", documents, test.wantId)
+			}
+			for _, document := range documents {
+				if !test.wantId[document.Id] {
+					t.Errorf("unexpected document %s after replay", document.Id)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreRemoveFailure(t *testing.T) {
+	store, err := OpenFileStore(t.TempDir(), "writes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Upsert("a", map[string]int{"v": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Remove("missing")
+	if !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Remove(missing) = %v, want ErrDocumentNotFound", err)
+	}
+
+	//with the log closed the append fails, the document must stay
+	store.Close()
+	err = store.Remove("a")
+	if err == nil {
+		t.Fatal("Remove with a closed log succeeded")
+	}
+
+	var value map[string]int
+	err = store.Get("a", &value)
+	if err != nil {
+		t.Errorf("Get after a failed Remove = %v, want the document", err)
+	}
+}
@@ -0,0 +1,57 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// syncMetadata are the document fields left out of the content hash.
+var syncMetadata = []string{"syncTs", "contentHash"}
+
+// contentHash is a stable hash of a mapped document without its sync metadata,
+// encoding/json sorts map keys so the same content always hashes the same.
+func contentHash(document interface{}) string {
+	content, err := json.Marshal(document)
+	if err != nil {
+		return ""
+	}
+
+	var fields map[string]interface{}
+	err = json.Unmarshal(content, &fields)
+	if err != nil {
+		return ""
+	}
+
+	for _, field := range syncMetadata {
+		delete(fields, field)
+	}
+
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// upsertIfChanged writes a reads document unless the stored one has the same content hash,
+// so unchanged records keep their SyncTs and don't replicate to every device again.
+func (s *Service) upsertIfChanged(id string, document interface{}, hash string) (bool, error) {
+	var stored struct {
+		ContentHash string `json:"contentHash"`
+	}
+	err := s.Reads.Get(id, &stored)
+
+	if err != nil && !errors.Is(err, ErrDocumentNotFound) {
+		return false, err
+	}
+
+	if err == nil && hash != "" && stored.ContentHash == hash {
+		return false, nil
+	}
+
+	return true, s.Reads.Upsert(id, document)
+}
@@ -0,0 +1,62 @@
+package data
+
+import "testing"
+
+func TestContentHash(t *testing.T) {
+	base := map[string]interface{}{"id": "clients_1", "displayName": "Ada", "officeId": 1}
+
+	tests := []struct {
+		name     string
+		document interface{}
+		same     bool
+	}{
+		{
+			name:     "same content",
+			document: map[string]interface{}{"officeId": 1, "displayName": "Ada", "id": "clients_1"},
+			same:     true,
+		},
+		{
+			name:     "sync metadata is ignored",
+			document: map[string]interface{}{"id": "clients_1", "displayName": "Ada", "officeId": 1, "syncTs": 1700000000, "contentHash": "stale"},
+			same:     true,
+		},
+		{
+			name: "struct with the same fields",
+			document: struct {
+				Id          string `json:"id"`
+				DisplayName string `json:"displayName"`
+				OfficeId    int    `json:"officeId"`
+			}{"clients_1", "Ada", 1},
+			same: true,
+		},
+		{
+			name:     "changed field",
+			document: map[string]interface{}{"id": "clients_1", "displayName": "Grace", "officeId": 1},
+		},
+		{
+			name:     "added field",
+			document: map[string]interface{}{"id": "clients_1", "displayName": "Ada", "officeId": 1, "mobileNo": "555"},
+		},
+	}
+
+	want := contentHash(base)
+	if want == "" {
+		t.Fatal("contentHash of a map is empty")
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := contentHash(test.document)
+
+			if (got == want) != test.same {
+				t.Errorf("contentHash(%v) = %s, base %s, want same = %v", test.document, got, want, test.same)
+			}
+		})
+	}
+}
+
+func TestContentHashUnencodable(t *testing.T) {
+	if hash := contentHash(make(chan int)); hash != "" {
+		t.Errorf("contentHash(chan) = %q, want empty", hash)
+	}
+}
@@ -0,0 +1,29 @@
+package data
+
+import (
+	"os"
+	"time"
+)
+
+// Lease is held by the replica running a task every replica schedules, e.g. reconciliation.
+// It expires on its own, so a replica that dies while holding it doesn't block the others.
+type Lease struct {
+	Id         string    `json:"_id"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	Type       string    `json:"type"`
+}
+
+// AcquireLease takes the named lease in the writes bucket for ttl, false while another replica holds it.
+func (s *Service) AcquireLease(name string, ttl time.Duration) (bool, error) {
+	err := s.ensureConnection()
+
+	if err != nil {
+		return false, err
+	}
+
+	holder, _ := os.Hostname()
+	id := "leases_" + name
+
+	return InsertWithExpiry(s.Writes, id, Lease{Id: id, Holder: holder, AcquiredAt: time.Now(), Type: "leases"}, ttl)
+}
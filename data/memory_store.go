@@ -0,0 +1,82 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore keeps documents as marshalled JSON, so values round-trip
+// the same way they would through Couchbase.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	docs map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		docs: map[string][]byte{},
+	}
+}
+
+func (m *MemoryStore) Get(id string, valuePtr interface{}) error {
+	m.mu.RLock()
+	content, ok := m.docs[id]
+	m.mu.RUnlock()
+
+	if !ok {
+		return ErrDocumentNotFound
+	}
+
+	return json.Unmarshal(content, valuePtr)
+}
+
+func (m *MemoryStore) Upsert(id string, value interface{}) error {
+	content, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.docs[id] = content
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *MemoryStore) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.docs[id]; !ok {
+		return ErrDocumentNotFound
+	}
+
+	delete(m.docs, id)
+	return nil
+}
+
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryStore) Query(prefix string) ([]Document, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var documents []Document
+	for id, content := range m.docs {
+		if strings.HasPrefix(id, prefix) {
+			documents = append(documents, Document{Id: id, Content: content})
+		}
+	}
+
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].Id < documents[j].Id
+	})
+
+	return documents, nil
+}
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"encoding/json"
+	"mock-server/dates"
+	"strconv"
+)
+
+// MigrationReport counts what MigrateActivationDates rewrote.
+type MigrationReport struct {
+	Scanned  int      `json:"scanned"`
+	Migrated int      `json:"migrated"`
+	Failed   []string `json:"failed"`
+}
+
+// MigrateActivationDates rewrites the activationDate of clients and groups stored before it
+// became an ISO date, when it was Fineract's date as an array of strings like ["2024","1","15"].
+// Without it every such document is rewritten by the next sync or reconciliation instead,
+// as its content no longer matches.
+func (s *Service) MigrateActivationDates() (MigrationReport, error) {
+	report := MigrationReport{Failed: []string{}}
+
+	err := s.ensureConnection()
+
+	if err != nil {
+		return report, err
+	}
+
+	documents, err := s.Reads.Query("")
+
+	if err != nil {
+		return report, err
+	}
+
+	for _, document := range documents {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(document.Content, &fields) != nil {
+			continue
+		}
+
+		var documentType string
+		json.Unmarshal(fields["type"], &documentType)
+		if documentType != "clients" && documentType != "groups" {
+			continue
+		}
+		report.Scanned++
+
+		var parts []string
+		if json.Unmarshal(fields["activationDate"], &parts) != nil {
+			continue
+		}
+
+		var date []int
+		for _, part := range parts {
+			number, err := strconv.Atoi(part)
+			if err != nil {
+				break
+			}
+			date = append(date, number)
+		}
+
+		activationDate := dates.FineractToISO(date)
+		if activationDate == "" {
+			report.Failed = append(report.Failed, document.Id)
+			continue
+		}
+
+		fields["activationDate"], _ = json.Marshal(activationDate)
+		err = s.Reads.Upsert(document.Id, fields)
+
+		if err != nil {
+			report.Failed = append(report.Failed, document.Id)
+			continue
+		}
+		report.Migrated++
+	}
+
+	return report, nil
+}
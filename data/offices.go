@@ -0,0 +1,140 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"mock-server/cliff"
+	"mock-server/shared"
+	"strconv"
+	"time"
+)
+
+// officesChannel is shared by every user, offices are small and not per district.
+const officesChannel = "offices"
+
+// Office is the reads document of a Fineract office, stored as offices_<id>.
+type Office struct {
+	Id          string   `json:"_id"`
+	OfficeId    int      `json:"officeId"`
+	Name        string   `json:"name"`
+	ExternalId  string   `json:"externalId"`
+	ParentId    int      `json:"parentId"`
+	ParentName  string   `json:"parentName"`
+	Hierarchy   string   `json:"hierarchy"`
+	Channels    []string `json:"channels"`
+	SyncTs      string   `json:"syncTs"`
+	ContentHash string   `json:"contentHash,omitempty"`
+	Type        string   `json:"type"`
+}
+
+func OfficeDocumentId(officeId int) string {
+	return "offices_" + strconv.Itoa(officeId)
+}
+
+// SaveOffices upserts Fineract offices into the reads bucket, like SaveClients.
+func (s *Service) SaveOffices(ctx context.Context, cliffOffices []shared.OfficeDTO, progress SyncProgress) error {
+	err := s.ensureConnection()
+
+	if err != nil {
+		return err
+	}
+
+	progress.AddTotal(len(cliffOffices))
+
+	for _, cliffOffice := range cliffOffices {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		office := convertCliffOfficeToOffice(cliffOffice)
+
+		changed, err := s.upsertIfChanged(office.Id, office, office.ContentHash)
+
+		switch {
+		case err != nil:
+			progress.Failed(office.Id, err)
+		case changed:
+			progress.Synced(office.Id)
+		default:
+			progress.Unchanged(office.Id)
+		}
+	}
+	return nil
+}
+
+// GetOffices returns every office synced into the reads bucket.
+func (s *Service) GetOffices() ([]Office, error) {
+	err := s.ensureConnection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.Reads.Query("offices_")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var offices []Office
+	for _, document := range documents {
+		var office Office
+		err = json.Unmarshal(document.Content, &office)
+
+		if err != nil {
+			return nil, err
+		}
+		offices = append(offices, office)
+	}
+	return offices, nil
+}
+
+// OfficeSubtree returns the synced office rootId and every office below it, root first.
+// It fails with ErrDocumentNotFound when rootId hasn't been synced.
+func (s *Service) OfficeSubtree(rootId int) ([]Office, error) {
+	offices, err := s.GetOffices()
+
+	if err != nil {
+		return nil, err
+	}
+
+	byId := map[int]Office{}
+	var hierarchy []shared.OfficeDTO
+	for _, office := range offices {
+		byId[office.OfficeId] = office
+		hierarchy = append(hierarchy, shared.OfficeDTO{Id: office.OfficeId, ParentId: office.ParentId})
+	}
+
+	if _, ok := byId[rootId]; !ok {
+		return nil, ErrDocumentNotFound
+	}
+
+	subtree, err := cliff.OfficeSubtree(hierarchy, rootId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Office
+	for _, office := range subtree {
+		result = append(result, byId[office.Id])
+	}
+	return result, nil
+}
+
+func convertCliffOfficeToOffice(cliffOffice shared.OfficeDTO) Office {
+	office := Office{
+		Id:         OfficeDocumentId(cliffOffice.Id),
+		OfficeId:   cliffOffice.Id,
+		Name:       cliffOffice.Name,
+		ExternalId: cliffOffice.ExternalId,
+		ParentId:   cliffOffice.ParentId,
+		ParentName: cliffOffice.ParentName,
+		Hierarchy:  cliffOffice.Hierarchy,
+		Channels:   []string{officesChannel},
+		SyncTs:     time.Now().Format("2006-01-02 15:04:05"),
+		Type:       "offices",
+	}
+	office.ContentHash = contentHash(office)
+	return office
+}
@@ -0,0 +1,56 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"mock-server/cliff"
+	"mock-server/workers"
+	"time"
+)
+
+// pendingLeaseTTL keeps replicas refreshing their code values together from resending the same requests.
+const pendingLeaseTTL = time.Minute
+
+// ResendPendingApiRequests sends the API requests a 503 left PENDING again, through the same
+// claim and worker as ProcessApiRequest. Only the replica taking the lease sweeps.
+// It returns how many requests were sent.
+func (s *Service) ResendPendingApiRequests(cliffService *cliff.Service) (int, error) {
+	acquired, err := s.AcquireLease("pending-api-requests", pendingLeaseTTL)
+
+	if err != nil || !acquired {
+		return 0, err
+	}
+
+	documents, err := s.Writes.Query("")
+
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, document := range documents {
+		var fields struct {
+			DocumentStates []DocumentState `json:"documentStates"`
+		}
+		if json.Unmarshal(document.Content, &fields) != nil || len(fields.DocumentStates) == 0 {
+			continue
+		}
+		if fields.DocumentStates[len(fields.DocumentStates)-1].Status != "PENDING" {
+			continue
+		}
+
+		err = s.ProcessApiRequest(document.Id, cliffService)
+
+		if errors.Is(err, workers.ErrDraining) {
+			return sent, err
+		}
+		if err != nil {
+			log.Println("Couldn't resend pending API request", document.Id, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
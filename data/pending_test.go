@@ -0,0 +1,62 @@
+package data
+
+import (
+	"mock-server/cliff"
+	"mock-server/workers"
+	"testing"
+	"time"
+)
+
+func TestResendPendingApiRequests(t *testing.T) {
+	service, cliffService, _ := newTestServices(t)
+	service.Workers = workers.NewGroup()
+	//code values aren't loaded yet, so a gender given by name can't be resolved
+	loaded := cliffService.Codes
+	cliffService.Codes = cliff.NewCodeCache(cliffService)
+
+	err := service.Writes.Upsert("request_1", ApiRequest{
+		Verb:        "POST",
+		RequestData: `{"clientBio":{"firstname":"Ada","lastname":"Lovelace","active":true,"legalForm":"PERSON","genderId":"Female"}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiRequest, err := service.ProcessApiRequestSync("request_1", cliffService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := lastState(apiRequest); apiRequest.ResponseStatusCode != 503 || status != "PENDING" {
+		t.Fatalf("before codes load: %d %s, want 503 PENDING", apiRequest.ResponseStatusCode, status)
+	}
+
+	cliffService.Codes = loaded
+
+	sent, err := service.ResendPendingApiRequests(cliffService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 {
+		t.Errorf("sent = %d, want 1", sent)
+	}
+
+	err = service.Workers.Drain(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = service.Writes.Get("request_1", &apiRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := lastState(apiRequest); apiRequest.ResponseStatusCode != 201 || status != "PROCESSED" {
+		t.Errorf("after codes load: %d %s %s, want 201 PROCESSED", apiRequest.ResponseStatusCode, status, apiRequest.ResponseData)
+	}
+}
+
+func lastState(apiRequest ApiRequest) string {
+	if len(apiRequest.DocumentStates) == 0 {
+		return ""
+	}
+	return apiRequest.DocumentStates[len(apiRequest.DocumentStates)-1].Status
+}
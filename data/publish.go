@@ -0,0 +1,146 @@
+package data
+
+import (
+	"fmt"
+	"log"
+	"mock-server/fakegen"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxReportedErrors caps how many failure messages a PublishReport carries.
+const maxReportedErrors = 20
+
+type PublishOptions struct {
+	Channels    []string `json:"channels"`
+	Clients     int      `json:"clients"`
+	Groups      int      `json:"groups"`
+	Concurrency int      `json:"concurrency"`
+	BatchSize   int      `json:"batchSize"`
+	OfficeId    int      `json:"officeId"`
+}
+
+type PublishReport struct {
+	Seed             int64    `json:"seed"`
+	ClientsPublished int      `json:"clientsPublished"`
+	ClientsFailed    int      `json:"clientsFailed"`
+	GroupsPublished  int      `json:"groupsPublished"`
+	GroupsFailed     int      `json:"groupsFailed"`
+	Errors           []string `json:"errors"`
+	Duration         string   `json:"duration"`
+}
+
+// PublishDocs seeds the reads bucket with fake groups and clients, every client belonging to one of the groups.
+func (s *Service) PublishDocs(options PublishOptions, generator *fakegen.Generator) (PublishReport, error) {
+	err := s.ensureConnection()
+
+	if err != nil {
+		log.Println(err)
+		return PublishReport{}, err
+	}
+
+	started := time.Now()
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1
+	}
+
+	clientChannel, groupChannel := "", ""
+	for _, channel := range options.Channels {
+		if strings.Contains(channel, "clients") {
+			clientChannel = channel
+		}
+		if strings.Contains(channel, "groups") {
+			groupChannel = channel
+		}
+	}
+	if options.OfficeId != 0 && clientChannel == "" {
+		clientChannel = "clients_" + strconv.Itoa(options.OfficeId)
+	}
+	if options.OfficeId != 0 && groupChannel == "" {
+		groupChannel = "groups_" + strconv.Itoa(options.OfficeId)
+	}
+
+	var items []BatchItem
+	var groups []Group
+	for i := 0; i < options.Groups; i++ {
+		group := fakeGroup(generator)
+		group.OfficeId = options.OfficeId
+		group.Channels = []string{groupChannel}
+		groups = append(groups, group)
+		items = append(items, BatchItem{Id: group.Id, Value: group})
+	}
+
+	for i := 0; i < options.Clients; i++ {
+		client := fakeClient(generator)
+		client.OfficeId = options.OfficeId
+		client.Channels = []string{clientChannel}
+		if len(groups) > 0 {
+			group := groups[i%len(groups)]
+			groupId, _ := strconv.Atoi(group.AccountNo)
+			client.Group = ClientGroup{Id: groupId, Name: group.Name, Leader: generator.FirstName() + " " + generator.LastName()}
+		}
+		items = append(items, BatchItem{Id: client.Id, Value: client})
+	}
+
+	batches := make(chan []BatchItem)
+	report := PublishReport{Seed: generator.Seed}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for w := 0; w < options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				errs := UpsertBatch(s.Reads, batch)
+
+				mu.Lock()
+				for i, err := range errs {
+					report.record(batch[i].Id, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for start := 0; start < len(items); start += options.BatchSize {
+		end := start + options.BatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		batches <- items[start:end]
+	}
+	close(batches)
+	wg.Wait()
+
+	report.Duration = time.Since(started).String()
+	log.Println("Published", report.ClientsPublished, "clients and", report.GroupsPublished, "groups,", report.ClientsFailed+report.GroupsFailed, "failed")
+	return report, nil
+}
+
+func (r *PublishReport) record(id string, err error) {
+	isClient := strings.HasPrefix(id, "clients_")
+
+	if err == nil {
+		if isClient {
+			r.ClientsPublished += 1
+		} else {
+			r.GroupsPublished += 1
+		}
+		return
+	}
+
+	if isClient {
+		r.ClientsFailed += 1
+	} else {
+		r.GroupsFailed += 1
+	}
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: %s", id, err))
+	}
+}
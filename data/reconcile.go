@@ -0,0 +1,235 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mock-server/cliff"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxReconcileSamples caps the differences kept per office in a report.
+const maxReconcileSamples = 20
+
+const (
+	DriftMissing  = "missing"
+	DriftStale    = "stale"
+	DriftOrphaned = "orphaned"
+)
+
+type Drift struct {
+	Id   string `json:"id"`
+	Kind string `json:"kind"`
+}
+
+type OfficeReconciliation struct {
+	OfficeId        string  `json:"officeId"`
+	FineractClients int     `json:"fineractClients"`
+	StoredClients   int     `json:"storedClients"`
+	FineractGroups  int     `json:"fineractGroups"`
+	StoredGroups    int     `json:"storedGroups"`
+	Missing         int     `json:"missing"`
+	Stale           int     `json:"stale"`
+	Orphaned        int     `json:"orphaned"`
+	Repaired        int     `json:"repaired"`
+	RepairFailed    int     `json:"repairFailed"`
+	DetailsFailed   int     `json:"detailsFailed"`
+	Samples         []Drift `json:"samples"`
+	Error           string  `json:"error,omitempty"`
+}
+
+type ReconcileReport struct {
+	Repair     bool                   `json:"repair"`
+	StartedAt  time.Time              `json:"startedAt"`
+	FinishedAt time.Time              `json:"finishedAt"`
+	Offices    []OfficeReconciliation `json:"offices"`
+}
+
+// Drifted counts every difference found across offices.
+func (r ReconcileReport) Drifted() int {
+	var drifted int
+	for _, office := range r.Offices {
+		drifted += office.Missing + office.Stale + office.Orphaned
+	}
+	return drifted
+}
+
+// Reconcile compares the clients and groups Fineract has for each office with the reads bucket.
+// With repair, missing and stale documents are upserted and orphaned ones removed.
+func (s *Service) Reconcile(ctx context.Context, officeIds []string, cliffService *cliff.Service, repair bool) (ReconcileReport, error) {
+	report := ReconcileReport{Repair: repair, StartedAt: time.Now()}
+
+	err := s.ensureConnection()
+
+	if err != nil {
+		return report, err
+	}
+
+	for _, officeId := range officeIds {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		office := OfficeReconciliation{OfficeId: officeId, Samples: []Drift{}}
+		err = s.reconcileOffice(ctx, &office, cliffService, repair)
+
+		if err != nil {
+			office.Error = err.Error()
+		}
+		report.Offices = append(report.Offices, office)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// reconcileOffice only reads the office's own clients and groups from the reads bucket.
+func (s *Service) reconcileOffice(ctx context.Context, office *OfficeReconciliation, cliffService *cliff.Service, repair bool) error {
+	numericId, err := strconv.Atoi(office.OfficeId)
+
+	if err != nil {
+		return fmt.Errorf("invalid office id %q", office.OfficeId)
+	}
+
+	storedClients, err := QueryOffice(s.Reads, "clients", numericId)
+
+	if err != nil {
+		return fmt.Errorf("stored clients: %w", err)
+	}
+
+	storedGroups, err := QueryOffice(s.Reads, "groups", numericId)
+
+	if err != nil {
+		return fmt.Errorf("stored groups: %w", err)
+	}
+
+	cliffClients, detailsFailed, err := cliffService.GetOfficeClients(ctx, office.OfficeId)
+
+	if err != nil {
+		return fmt.Errorf("fineract clients: %w", err)
+	}
+
+	//a client whose details failed is neither compared nor taken for an orphan
+	skipped := map[string]bool{}
+	for _, failure := range detailsFailed {
+		skipped["clients_"+failure.Client.AccountNo] = true
+		office.DetailsFailed++
+	}
+
+	cliffGroups, err := cliffService.GetOfficeGroups(office.OfficeId)
+
+	if err != nil {
+		return fmt.Errorf("fineract groups: %w", err)
+	}
+
+	expected := map[string]interface{}{}
+	for _, cliffClient := range cliffClients {
+		client := convertCliffClientToClient(cliffClient)
+		expected[client.Id] = client
+	}
+	for _, cliffGroup := range cliffGroups {
+		group := convertCliffGroupToGroup(cliffGroup)
+		expected[group.Id] = group
+	}
+
+	existing := map[string]Document{}
+	for _, document := range append(storedClients, storedGroups...) {
+		existing[document.Id] = document
+	}
+
+	office.FineractClients = len(cliffClients)
+	office.FineractGroups = len(cliffGroups)
+	office.StoredClients = len(storedClients)
+	office.StoredGroups = len(storedGroups)
+
+	var ids []string
+	for id := range expected {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		document, ok := existing[id]
+
+		switch {
+		case !ok:
+			office.Missing++
+			office.record(Drift{Id: id, Kind: DriftMissing})
+		case !sameContent(document.Content, expected[id]):
+			office.Stale++
+			office.record(Drift{Id: id, Kind: DriftStale})
+		default:
+			continue
+		}
+
+		if repair {
+			office.repaired(s.Reads.Upsert(id, expected[id]))
+		}
+	}
+
+	var orphans []string
+	for id := range existing {
+		if _, ok := expected[id]; !ok && !skipped[id] {
+			orphans = append(orphans, id)
+		}
+	}
+	sort.Strings(orphans)
+
+	for _, id := range orphans {
+		office.Orphaned++
+		office.record(Drift{Id: id, Kind: DriftOrphaned})
+
+		if repair && s.stillInOffice(id, numericId) {
+			office.repaired(s.Reads.Remove(id))
+		}
+	}
+
+	return nil
+}
+
+// stillInOffice guards orphan removal against a document another office's repair
+// already moved since the office's documents were read.
+func (s *Service) stillInOffice(id string, officeId int) bool {
+	var fields struct {
+		OfficeId int `json:"officeId"`
+	}
+	err := s.Reads.Get(id, &fields)
+	return err == nil && fields.OfficeId == officeId
+}
+
+func (o *OfficeReconciliation) record(drift Drift) {
+	if len(o.Samples) < maxReconcileSamples {
+		o.Samples = append(o.Samples, drift)
+	}
+}
+
+func (o *OfficeReconciliation) repaired(err error) {
+	if err != nil {
+		o.RepairFailed++
+		return
+	}
+	o.Repaired++
+}
+
+// sameContent compares a stored document with a freshly mapped one, ignoring sync metadata.
+// Unlike the content hash it catches documents edited in the bucket directly.
+func sameContent(stored json.RawMessage, fresh interface{}) bool {
+	freshContent, err := json.Marshal(fresh)
+	if err != nil {
+		return false
+	}
+
+	var storedFields, freshFields map[string]interface{}
+	if json.Unmarshal(stored, &storedFields) != nil || json.Unmarshal(freshContent, &freshFields) != nil {
+		return false
+	}
+
+	for _, field := range syncMetadata {
+		delete(storedFields, field)
+		delete(freshFields, field)
+	}
+	return reflect.DeepEqual(storedFields, freshFields)
+}
@@ -0,0 +1,52 @@
+package data
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ExportSnapshot writes every document of the store as one JSON line.
+func ExportSnapshot(store DocumentStore, w io.Writer) (int, error) {
+	documents, err := store.Query("")
+
+	if err != nil {
+		return 0, err
+	}
+
+	encoder := json.NewEncoder(w)
+	for i, document := range documents {
+		err = encoder.Encode(document)
+
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(documents), nil
+}
+
+// ImportSnapshot upserts every document of a snapshot written by ExportSnapshot.
+func ImportSnapshot(store DocumentStore, r io.Reader) (int, error) {
+	decoder := json.NewDecoder(r)
+	imported := 0
+
+	for {
+		var document Document
+		err := decoder.Decode(&document)
+
+		if err == io.EOF {
+			return imported, nil
+		}
+
+		if err != nil {
+			return imported, err
+		}
+
+		err = store.Upsert(document.Id, document.Content)
+
+		if err != nil {
+			return imported, err
+		}
+		imported += 1
+	}
+}
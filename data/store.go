@@ -0,0 +1,105 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrDocumentNotFound = errors.New("document not found")
+
+// Document is a raw stored document together with its key.
+type Document struct {
+	Id      string          `json:"id"`
+	Content json.RawMessage `json:"doc"`
+}
+
+// DocumentStore is the subset of bucket operations the Service needs,
+// implemented once for Couchbase and once in memory.
+type DocumentStore interface {
+	Get(id string, valuePtr interface{}) error
+	Upsert(id string, value interface{}) error
+	Remove(id string) error
+	//Query returns every document whose id starts with prefix
+	Query(prefix string) ([]Document, error)
+	//Ping reports whether the store can currently serve requests, giving up when ctx is done
+	Ping(ctx context.Context) error
+}
+
+// BatchItem is one document of a batched upsert.
+type BatchItem struct {
+	Id    string
+	Value interface{}
+}
+
+// BatchUpserter is implemented by stores that can write many documents in one round trip.
+type BatchUpserter interface {
+	UpsertBatch(items []BatchItem) []error
+}
+
+// OfficeQuerier is implemented by stores that can select the documents of a type and office
+// without reading every document of the type.
+type OfficeQuerier interface {
+	QueryOffice(documentType string, officeId int) ([]Document, error)
+}
+
+// QueryOffice returns the documents of a type, e.g. clients, that belong to an office.
+// Stores without OfficeQuerier are in memory and simply scanned, ids don't always carry the type,
+// e.g. groups synced from Fineract.
+func QueryOffice(store DocumentStore, documentType string, officeId int) ([]Document, error) {
+	if officeQuerier, ok := store.(OfficeQuerier); ok {
+		return officeQuerier.QueryOffice(documentType, officeId)
+	}
+
+	documents, err := store.Query("")
+	if err != nil {
+		return nil, err
+	}
+
+	var inOffice []Document
+	for _, document := range documents {
+		var fields struct {
+			Type     string `json:"type"`
+			OfficeId int    `json:"officeId"`
+		}
+		if json.Unmarshal(document.Content, &fields) != nil {
+			continue
+		}
+		if fields.Type == documentType && fields.OfficeId == officeId {
+			inOffice = append(inOffice, document)
+		}
+	}
+	return inOffice, nil
+}
+
+// ExpiringInserter is implemented by stores shared between replicas, which can create
+// a document only when it doesn't exist yet and have it expire.
+type ExpiringInserter interface {
+	InsertWithExpiry(id string, value interface{}, expiry time.Duration) (bool, error)
+}
+
+// InsertWithExpiry creates the document unless it exists, false when it does. A store without
+// ExpiringInserter only serves one process and can't expire documents, the document is upserted.
+func InsertWithExpiry(store DocumentStore, id string, value interface{}, expiry time.Duration) (bool, error) {
+	if inserter, ok := store.(ExpiringInserter); ok {
+		return inserter.InsertWithExpiry(id, value, expiry)
+	}
+
+	err := store.Upsert(id, value)
+	return err == nil, err
+}
+
+// UpsertBatch writes items in one round trip when the store supports it, one by one otherwise.
+// The returned errors line up with items.
+func UpsertBatch(store DocumentStore, items []BatchItem) []error {
+	if batchUpserter, ok := store.(BatchUpserter); ok {
+		return batchUpserter.UpsertBatch(items)
+	}
+
+	errs := make([]error, len(items))
+	for i, item := range items {
+		errs[i] = store.Upsert(item.Id, item.Value)
+	}
+	return errs
+}
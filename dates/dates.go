@@ -0,0 +1,170 @@
+package dates
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ISOLayout is how dates are stored in the reads bucket.
+const ISOLayout = "2006-01-02"
+
+// RequestFormat is the dateFormat of the dates sent to Fineract,
+// numeric so it parses the same whatever locale the request carries.
+const RequestFormat = "yyyy-MM-dd"
+
+// patternTokens maps Fineract's (Java) date pattern letters to Go layout elements, longest first.
+var patternTokens = []struct {
+	pattern string
+	layout  string
+	named   bool
+}{
+	{"yyyy", "2006", false},
+	{"yy", "06", false},
+	{"MMMM", "January", true},
+	{"MMM", "Jan", true},
+	{"MM", "01", false},
+	{"M", "1", false},
+	{"dd", "02", false},
+	{"d", "2", false},
+}
+
+// Layout turns a Fineract dateFormat like "dd MMMM yyyy" into a Go layout.
+// Month names only exist in English, so a pattern with them needs an English or empty locale.
+func Layout(dateFormat string, locale string) (string, error) {
+	var layout strings.Builder
+	var named bool
+
+	for rest := dateFormat; rest != ""; {
+		matched := false
+		for _, token := range patternTokens {
+			if strings.HasPrefix(rest, token.pattern) {
+				layout.WriteString(token.layout)
+				named = named || token.named
+				rest = rest[len(token.pattern):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		c := rest[0]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			return "", fmt.Errorf("unsupported dateFormat %q", dateFormat)
+		}
+		layout.WriteByte(c)
+		rest = rest[1:]
+	}
+
+	if layout.Len() == 0 {
+		return "", errors.New("empty dateFormat")
+	}
+
+	if named && locale != "" && !strings.HasPrefix(strings.ToLower(locale), "en") {
+		return "", fmt.Errorf("month names are only supported for English, not locale %q", locale)
+	}
+
+	return layout.String(), nil
+}
+
+// Parse reads a date written with a Fineract dateFormat and locale.
+func Parse(value string, dateFormat string, locale string) (time.Time, error) {
+	layout, err := Layout(dateFormat, locale)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(layout, strings.TrimSpace(value))
+}
+
+// Format writes a date with a Fineract dateFormat.
+func Format(date time.Time, dateFormat string) (string, error) {
+	layout, err := Layout(dateFormat, "")
+
+	if err != nil {
+		return "", err
+	}
+
+	return date.Format(layout), nil
+}
+
+// ParseISO reads an ISO-8601 date, with or without a time.
+func ParseISO(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	date, err := time.Parse(ISOLayout, value)
+	if err == nil {
+		return date, nil
+	}
+
+	date, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ISO-8601 date %q", value)
+	}
+
+	return date, nil
+}
+
+// ISO writes the date part of a time, the format of the reads bucket.
+func ISO(date time.Time) string {
+	return date.Format(ISOLayout)
+}
+
+// ParseRequest reads a date sent by a device, in its dateFormat and locale when it has one,
+// otherwise, or when that fails, as ISO-8601. An empty value is the zero time.
+func ParseRequest(value string, dateFormat string, locale string) (time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return time.Time{}, nil
+	}
+
+	if dateFormat != "" {
+		date, err := Parse(value, dateFormat, locale)
+		if err == nil {
+			return date, nil
+		}
+
+		date, isoErr := ParseISO(value)
+		if isoErr != nil {
+			return time.Time{}, fmt.Errorf("date %q doesn't match dateFormat %q: %w", value, dateFormat, err)
+		}
+		return date, nil
+	}
+
+	return ParseISO(value)
+}
+
+// FromFineract reads Fineract's [yyyy, m, d] date arrays, false when the array isn't a valid date.
+func FromFineract(date []int) (time.Time, bool) {
+	if len(date) != 3 {
+		return time.Time{}, false
+	}
+
+	t := time.Date(date[0], time.Month(date[1]), date[2], 0, 0, 0, 0, time.UTC)
+
+	//time.Date normalizes out of range days and months instead of failing
+	if t.Year() != date[0] || int(t.Month()) != date[1] || t.Day() != date[2] {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+// ToFineract writes a date as Fineract's [yyyy, m, d] array.
+func ToFineract(date time.Time) []int {
+	return []int{date.Year(), int(date.Month()), date.Day()}
+}
+
+// FineractToISO converts a Fineract date array to the reads bucket format, empty when unset.
+func FineractToISO(date []int) string {
+	t, ok := FromFineract(date)
+
+	if !ok {
+		return ""
+	}
+
+	return ISO(t)
+}
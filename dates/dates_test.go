@@ -0,0 +1,84 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		dateFormat string
+		locale     string
+		want       string
+		wantErr    bool
+	}{
+		{dateFormat: "dd MMMM yyyy", locale: "en", want: "02 January 2006"},
+		{dateFormat: "dd MMM yyyy", locale: "en_GB", want: "02 Jan 2006"},
+		{dateFormat: "yyyy-MM-dd", want: "2006-01-02"},
+		{dateFormat: "d/M/yy", locale: "fr", want: "2/1/06"},
+		{dateFormat: "dd.MM.yyyy", locale: "de", want: "02.01.2006"},
+		{dateFormat: "dd MMMM yyyy", locale: "fr", wantErr: true},
+		{dateFormat: "dd-MM-yyyy HH:mm", wantErr: true},
+		{dateFormat: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.dateFormat+"/"+test.locale, func(t *testing.T) {
+			layout, err := Layout(test.dateFormat, test.locale)
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Layout(%q, %q) = %q, want an error", test.dateFormat, test.locale, layout)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if layout != test.want {
+				t.Errorf("Layout(%q, %q) = %q, want %q", test.dateFormat, test.locale, layout, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	march := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		value      string
+		dateFormat string
+		locale     string
+		want       time.Time
+		wantErr    bool
+	}{
+		{name: "empty is zero", value: " ", dateFormat: "dd MMMM yyyy", want: time.Time{}},
+		{name: "dateFormat", value: "05 March 2024", dateFormat: "dd MMMM yyyy", locale: "en", want: march},
+		{name: "numeric dateFormat", value: "5/3/2024", dateFormat: "d/M/yyyy", locale: "fr", want: march},
+		{name: "ISO without dateFormat", value: "2024-03-05", want: march},
+		{name: "RFC 3339 without dateFormat", value: "2024-03-05T00:00:00Z", want: march},
+		{name: "ISO fallback", value: "2024-03-05", dateFormat: "dd MMMM yyyy", locale: "en", want: march},
+		{name: "neither dateFormat nor ISO", value: "March 5th", dateFormat: "dd MMMM yyyy", locale: "en", wantErr: true},
+		{name: "not ISO", value: "05/03/2024", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			date, err := ParseRequest(test.value, test.dateFormat, test.locale)
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRequest(%q, %q, %q) = %v, want an error", test.value, test.dateFormat, test.locale, date)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !date.Equal(test.want) {
+				t.Errorf("ParseRequest(%q, %q, %q) = %v, want %v", test.value, test.dateFormat, test.locale, date, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package fakegen
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReference is the time generated dates are relative to,
+// fixed rather than time.Now() so a seed yields the same data on any day.
+var DefaultReference = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Generator produces fake data from its own seeded source,
+// so the same seed and locale always yield the same sequence.
+type Generator struct {
+	Seed   int64
+	Locale Locale
+	// Reference is "now" for generated dates, activation dates and sync timestamps
+	Reference time.Time
+	mu        sync.Mutex
+	rand      *rand.Rand
+}
+
+// New returns a generator for the locale code (ke, rw, ug, tz, mw).
+// A zero seed picks a time based one, which is kept on the generator so it can be logged.
+func New(seed int64, locale string) (*Generator, error) {
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	l, ok := Locales[strings.ToLower(locale)]
+	if !ok {
+		return nil, fmt.Errorf("unknown fake data locale %q", locale)
+	}
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &Generator{
+		Seed:      seed,
+		Locale:    l,
+		Reference: DefaultReference,
+		rand:      rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// Between returns a number in [low, high].
+func (g *Generator) Between(low int, high int) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return low + g.rand.Intn(high-low+1)
+}
+
+func (g *Generator) pick(values []string) string {
+	return values[g.Between(0, len(values)-1)]
+}
+
+func (g *Generator) digits(n int) string {
+	var builder strings.Builder
+	for i := 0; i < n; i++ {
+		builder.WriteByte(byte('0' + g.Between(0, 9)))
+	}
+	return builder.String()
+}
+
+// Person returns a first name with its matching gender, "M" or "F".
+func (g *Generator) Person() (string, string) {
+	if g.Between(0, 1) == 0 {
+		return g.pick(g.Locale.MaleFirstNames), "M"
+	}
+	return g.pick(g.Locale.FemaleFirstNames), "F"
+}
+
+func (g *Generator) FirstName() string {
+	name, _ := g.Person()
+	return name
+}
+
+func (g *Generator) LastName() string {
+	return g.pick(g.Locale.LastNames)
+}
+
+func (g *Generator) PhoneNumber() string {
+	return g.pick(g.Locale.PhonePrefixes) + g.digits(g.Locale.PhoneDigits)
+}
+
+func (g *Generator) AccountNo() string {
+	return g.digits(9)
+}
+
+func (g *Generator) NationalId() string {
+	return g.digits(8)
+}
+
+func (g *Generator) Place() string {
+	return g.pick(g.Locale.Places)
+}
+
+func (g *Generator) Email(firstname string, lastname string, domain string) string {
+	local := strings.ToLower(firstname + "." + lastname)
+	return strings.Replace(local, " ", "", -1) + "@" + domain
+}
+
+// Coordinates returns a point inside the locale's country bounding box.
+func (g *Generator) Coordinates() (float32, float32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	latitude := g.Locale.MinLat + g.rand.Float32()*(g.Locale.MaxLat-g.Locale.MinLat)
+	longitude := g.Locale.MinLong + g.rand.Float32()*(g.Locale.MaxLong-g.Locale.MinLong)
+	return latitude, longitude
+}
+
+// Date returns a day between from and to.
+func (g *Generator) Date(from time.Time, to time.Time) time.Time {
+	days := int(to.Sub(from).Hours() / 24)
+	if days <= 0 {
+		return from
+	}
+	return from.AddDate(0, 0, g.Between(0, days))
+}
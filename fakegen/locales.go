@@ -0,0 +1,96 @@
+package fakegen
+
+// Locale holds the country specific pools fake data is drawn from.
+type Locale struct {
+	Code             string
+	Country          string
+	MaleFirstNames   []string
+	FemaleFirstNames []string
+	LastNames        []string
+	PhonePrefixes    []string
+	PhoneDigits      int
+	Places           []string
+	LevelNames       []string
+	MinLat, MaxLat   float32
+	MinLong, MaxLong float32
+}
+
+var Locales = map[string]Locale{
+	"ke": {
+		Code:             "ke",
+		Country:          "Kenya",
+		MaleFirstNames:   []string{"Brian", "Kevin", "Dennis", "Collins", "Wycliffe", "Geoffrey", "Samuel", "Joseph"},
+		FemaleFirstNames: []string{"Mercy", "Faith", "Esther", "Caroline", "Purity", "Winnie", "Beatrice", "Naomi"},
+		LastNames:        []string{"Wafula", "Wanjala", "Barasa", "Otieno", "Ochieng", "Wekesa", "Simiyu", "Makokha", "Nafula", "Juma", "Mutua", "Kamau"},
+		PhonePrefixes:    []string{"+25470", "+25471", "+25472", "+25479", "+25411"},
+		PhoneDigits:      7,
+		Places:           []string{"Bungoma", "Kakamega", "Busia", "Siaya", "Migori", "Kisii", "Nyamira", "Homa Bay", "Trans Nzoia", "Vihiga"},
+		LevelNames:       []string{"Country", "Region", "District", "Site"},
+		MinLat:           -4.7,
+		MaxLat:           4.6,
+		MinLong:          33.9,
+		MaxLong:          41.9,
+	},
+	"rw": {
+		Code:             "rw",
+		Country:          "Rwanda",
+		MaleFirstNames:   []string{"Jean Claude", "Eric", "Emmanuel", "Innocent", "Patrick", "Jean de Dieu", "Olivier", "Theogene"},
+		FemaleFirstNames: []string{"Claudine", "Diane", "Aline", "Josiane", "Vestine", "Jeanne", "Solange", "Chantal"},
+		LastNames:        []string{"Uwimana", "Niyonzima", "Mukamana", "Habimana", "Nshimiyimana", "Uwase", "Ndayisaba", "Mugisha", "Nyiraneza", "Hakizimana", "Bizimana", "Iradukunda"},
+		PhonePrefixes:    []string{"+25078", "+25072", "+25073"},
+		PhoneDigits:      7,
+		Places:           []string{"Huye", "Nyanza", "Musanze", "Rubavu", "Nyagatare", "Kayonza", "Rwamagana", "Gisagara", "Karongi", "Rusizi"},
+		LevelNames:       []string{"Country", "Province", "District", "Sector"},
+		MinLat:           -2.84,
+		MaxLat:           -1.05,
+		MinLong:          28.86,
+		MaxLong:          30.9,
+	},
+	"ug": {
+		Code:             "ug",
+		Country:          "Uganda",
+		MaleFirstNames:   []string{"Moses", "Ronald", "Isaac", "Godfrey", "Denis", "Robert"},
+		FemaleFirstNames: []string{"Grace", "Prossy", "Harriet", "Sarah", "Florence", "Agnes"},
+		LastNames:        []string{"Okello", "Mukasa", "Namubiru", "Wandera", "Opio", "Nakato", "Kato", "Ssempala", "Achieng", "Waiswa"},
+		PhonePrefixes:    []string{"+25677", "+25678", "+25670", "+25675"},
+		PhoneDigits:      7,
+		Places:           []string{"Mbale", "Tororo", "Busia", "Iganga", "Jinja", "Soroti"},
+		LevelNames:       []string{"Country", "Region", "District", "Sub-county"},
+		MinLat:           -1.48,
+		MaxLat:           4.23,
+		MinLong:          29.57,
+		MaxLong:          35.0,
+	},
+	"tz": {
+		Code:             "tz",
+		Country:          "Tanzania",
+		MaleFirstNames:   []string{"Baraka", "Juma", "Emmanuel", "Hamisi", "Amani", "Daudi"},
+		FemaleFirstNames: []string{"Neema", "Rehema", "Upendo", "Zawadi", "Halima", "Mwanaisha"},
+		LastNames:        []string{"Mwakyusa", "Kimaro", "Mushi", "Mollel", "Massawe", "Mwakasege", "Ngowi", "Lyimo", "Shayo", "Mrema"},
+		PhonePrefixes:    []string{"+25571", "+25574", "+25575", "+25576", "+25578"},
+		PhoneDigits:      7,
+		Places:           []string{"Iringa", "Mbeya", "Njombe", "Songwe", "Ruvuma"},
+		LevelNames:       []string{"Country", "Region", "District", "Ward"},
+		MinLat:           -11.7,
+		MaxLat:           -0.99,
+		MinLong:          29.3,
+		MaxLong:          40.4,
+	},
+	"mw": {
+		Code:             "mw",
+		Country:          "Malawi",
+		MaleFirstNames:   []string{"Chikondi", "Kondwani", "Blessings", "Mphatso", "Yamikani", "Limbani"},
+		FemaleFirstNames: []string{"Chisomo", "Tiyamike", "Thoko", "Mercy", "Grace", "Tadala"},
+		LastNames:        []string{"Banda", "Phiri", "Mwale", "Chirwa", "Kumwenda", "Nyirenda", "Gondwe", "Tembo", "Zulu", "Mbewe"},
+		PhonePrefixes:    []string{"+26599", "+26588"},
+		PhoneDigits:      7,
+		Places:           []string{"Lilongwe", "Dedza", "Ntcheu", "Mzimba", "Kasungu", "Zomba"},
+		LevelNames:       []string{"Country", "Region", "District", "Zone"},
+		MinLat:           -17.1,
+		MaxLat:           -9.37,
+		MinLong:          32.7,
+		MaxLong:          35.9,
+	},
+}
+
+const DefaultLocale = "ke"
@@ -0,0 +1,153 @@
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	TargetCliff = "cliff"
+	TargetStore = "store"
+)
+
+// Rule describes a fault and the calls it applies to.
+type Rule struct {
+	Id     string `json:"id"`
+	Target string `json:"target"`
+	//Operation is an HTTP method for cliff, or get, upsert, remove or query for store; empty matches all
+	Operation string `json:"operation"`
+	//Bucket restricts store faults to "reads" or "writes"
+	Bucket string `json:"bucket"`
+	//Pattern is a path.Match pattern on the URL path for cliff and the document id for store
+	Pattern string `json:"pattern"`
+	//Probability in (0, 1], 0 means always
+	Probability   float64 `json:"probability"`
+	Latency       string  `json:"latency"`
+	Timeout       bool    `json:"timeout"`
+	StatusCode    int     `json:"statusCode"`
+	MalformedJSON bool    `json:"malformedJson"`
+	DropWrite     bool    `json:"dropWrite"`
+	latency       time.Duration
+}
+
+var ErrTimeout = timeoutError{}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "injected timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// Injector holds the active fault rules, it is safe to change them while calls are in flight.
+type Injector struct {
+	mu     sync.Mutex
+	rules  []Rule
+	nextId int
+	rand   *rand.Rand
+}
+
+func NewInjector() *Injector {
+	return &Injector{
+		nextId: 1,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (i *Injector) Rules() []Rule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return append([]Rule{}, i.rules...)
+}
+
+func (i *Injector) Add(rule Rule) (Rule, error) {
+	if rule.Target != TargetCliff && rule.Target != TargetStore {
+		return Rule{}, fmt.Errorf("fault target must be %q or %q", TargetCliff, TargetStore)
+	}
+
+	if rule.Probability < 0 || rule.Probability > 1 {
+		return Rule{}, errors.New("fault probability must be between 0 and 1")
+	}
+
+	if _, err := path.Match(rule.Pattern, ""); err != nil {
+		return Rule{}, fmt.Errorf("invalid fault pattern %q", rule.Pattern)
+	}
+
+	if rule.Latency != "" {
+		latency, err := time.ParseDuration(rule.Latency)
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid fault latency %q", rule.Latency)
+		}
+		rule.latency = latency
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	rule.Id = strconv.Itoa(i.nextId)
+	i.nextId += 1
+	i.rules = append(i.rules, rule)
+	return rule, nil
+}
+
+func (i *Injector) Remove(id string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for index, rule := range i.rules {
+		if rule.Id == id {
+			i.rules = append(i.rules[:index], i.rules[index+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Injector) Clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules = nil
+}
+
+// Match returns the first rule that applies to the call and wins its probability roll.
+func (i *Injector) Match(target string, bucket string, operation string, name string) (Rule, bool) {
+	if i == nil {
+		return Rule{}, false
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, rule := range i.rules {
+		if rule.Target != target {
+			continue
+		}
+		if rule.Bucket != "" && rule.Bucket != bucket {
+			continue
+		}
+		if rule.Operation != "" && rule.Operation != operation {
+			continue
+		}
+		if matched, _ := path.Match(rule.Pattern, name); rule.Pattern != "" && !matched {
+			continue
+		}
+		if rule.Probability > 0 && i.rand.Float64() >= rule.Probability {
+			continue
+		}
+		return rule, true
+	}
+	return Rule{}, false
+}
+
+// Delay sleeps for the rule's latency.
+func (r Rule) Delay() {
+	if r.latency > 0 {
+		time.Sleep(r.latency)
+	}
+}
@@ -0,0 +1,131 @@
+package faults
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "cliff", rule: Rule{Target: TargetCliff, StatusCode: 500}},
+		{name: "store with latency", rule: Rule{Target: TargetStore, Latency: "10ms"}},
+		{name: "unknown target", rule: Rule{Target: "sgw"}, wantErr: true},
+		{name: "probability above 1", rule: Rule{Target: TargetCliff, Probability: 1.5}, wantErr: true},
+		{name: "invalid pattern", rule: Rule{Target: TargetCliff, Pattern: "["}, wantErr: true},
+		{name: "invalid latency", rule: Rule{Target: TargetCliff, Latency: "slow"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := NewInjector().Add(test.rule)
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Add = %v, want error %v", err, test.wantErr)
+			}
+			if err == nil && rule.Id == "" {
+				t.Error("added rule has no id")
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	injector := NewInjector()
+	for _, rule := range []Rule{
+		{Target: TargetStore, Bucket: "writes", Operation: "upsert", Pattern: "jobs_*", Timeout: true},
+		{Target: TargetCliff, Operation: "POST", StatusCode: 500},
+	} {
+		_, err := injector.Add(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		target    string
+		bucket    string
+		operation string
+		name      string
+		wantId    string
+	}{
+		{target: TargetStore, bucket: "writes", operation: "upsert", name: "jobs_1", wantId: "1"},
+		{target: TargetStore, bucket: "reads", operation: "upsert", name: "jobs_1"},
+		{target: TargetStore, bucket: "writes", operation: "get", name: "jobs_1"},
+		{target: TargetStore, bucket: "writes", operation: "upsert", name: "clients_1"},
+		{target: TargetCliff, operation: "POST", name: "/clients", wantId: "2"},
+		{target: TargetCliff, operation: "GET", name: "/clients"},
+	}
+
+	for _, test := range tests {
+		rule, ok := injector.Match(test.target, test.bucket, test.operation, test.name)
+		if ok != (test.wantId != "") || rule.Id != test.wantId {
+			t.Errorf("Match(%s, %s, %s, %s) = %q %v, want %q", test.target, test.bucket, test.operation, test.name, rule.Id, ok, test.wantId)
+		}
+	}
+
+	if !injector.Remove("1") || len(injector.Rules()) != 1 {
+		t.Errorf("Remove left %v", injector.Rules())
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1,"name":"head office"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		rule       Rule
+		method     string
+		wantErr    error
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "no fault", rule: Rule{Target: TargetCliff, Pattern: "/other"}, method: "GET", wantStatus: 200, wantBody: `{"id":1,"name":"head office"}`},
+		{name: "timeout", rule: Rule{Target: TargetCliff, Timeout: true}, method: "GET", wantErr: ErrTimeout},
+		{name: "status code", rule: Rule{Target: TargetCliff, StatusCode: 503}, method: "GET", wantStatus: 503},
+		{name: "dropped write", rule: Rule{Target: TargetCliff, DropWrite: true}, method: "POST", wantStatus: 200, wantBody: "{}"},
+		{name: "malformed json", rule: Rule{Target: TargetCliff, MalformedJSON: true}, method: "GET", wantStatus: 200, wantBody: `{"id":1,"name"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			injector := NewInjector()
+			_, err := injector.Add(test.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: &Transport{Injector: injector}}
+
+			request, _ := http.NewRequest(test.method, server.URL+"/clients", nil)
+			response, err := client.Do(request)
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("Do = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+
+			body, _ := ioutil.ReadAll(response.Body)
+			if response.StatusCode != test.wantStatus {
+				t.Errorf("status = %d, want %d", response.StatusCode, test.wantStatus)
+			}
+			if test.wantBody != "" && string(body) != test.wantBody {
+				t.Errorf("body = %s, want %s", body, test.wantBody)
+			}
+		})
+	}
+}
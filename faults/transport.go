@@ -0,0 +1,69 @@
+package faults
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Transport injects cliff faults in front of the real HTTP transport.
+type Transport struct {
+	Injector *Injector
+	Next     http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	rule, ok := t.Injector.Match(TargetCliff, "", request.Method, request.URL.Path)
+	if !ok {
+		return next.RoundTrip(request)
+	}
+
+	rule.Delay()
+
+	switch {
+	case rule.Timeout:
+		return nil, ErrTimeout
+	case rule.DropWrite && request.Method != "GET":
+		return fakeResponse(request, http.StatusOK, []byte("{}")), nil
+	case rule.StatusCode != 0:
+		body := fmt.Sprintf(`{"httpStatusCode":"%d","defaultUserMessage":"injected fault","userMessageGlobalisationCode":"error.msg.injected.fault"}`, rule.StatusCode)
+		return fakeResponse(request, rule.StatusCode, []byte(body)), nil
+	}
+
+	response, err := next.RoundTrip(request)
+	if err != nil || !rule.MalformedJSON {
+		return response, err
+	}
+
+	//cut the real body in half so it no longer parses
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body = body[:len(body)/2]
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	response.ContentLength = int64(len(body))
+	response.Header.Del("Content-Length")
+	return response, nil
+}
+
+func fakeResponse(request *http.Request, statusCode int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
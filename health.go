@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"mock-server/auth"
+	"mock-server/cliff"
+	"mock-server/config"
+	"mock-server/data"
+	"mock-server/router"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readinessTimeout bounds each dependency check of /readyz.
+const readinessTimeout = 5 * time.Second
+
+type DependencyStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+func registerHealthRoutes(mux *router.Router, cfg config.Config, couchbaseService *data.Service, cliffService *cliff.Service) {
+
+	//Liveness: the process is up and serving
+	mux.Get("/healthz", func(w http.ResponseWriter, r *http.Request) error {
+		return router.JSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	//Readiness: every dependency answers, checked concurrently
+	mux.Get("/readyz", func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		checks := map[string]func(ctx context.Context) error{
+			"store":    couchbaseService.Ping,
+			"fineract": cliffService.Ping,
+			"syncGateway": func(ctx context.Context) error {
+				return auth.PingSGW(ctx, cfg.SGWBaseURL)
+			},
+		}
+
+		response := ReadinessResponse{Status: "ready", Dependencies: map[string]DependencyStatus{}}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+
+		for name, check := range checks {
+			wg.Add(1)
+			go func(name string, check func(ctx context.Context) error) {
+				defer wg.Done()
+
+				started := time.Now()
+				err := check(ctx)
+				status := DependencyStatus{Status: "up", LatencyMs: time.Since(started).Milliseconds()}
+				if err != nil {
+					status.Status = "down"
+					status.Error = err.Error()
+				}
+
+				mu.Lock()
+				response.Dependencies[name] = status
+				if err != nil {
+					response.Status = "not_ready"
+				}
+				mu.Unlock()
+			}(name, check)
+		}
+		wg.Wait()
+
+		if response.Status != "ready" {
+			return router.JSON(w, http.StatusServiceUnavailable, response)
+		}
+		return router.JSON(w, http.StatusOK, response)
+	})
+}
@@ -0,0 +1,458 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	uuid2 "github.com/google/uuid"
+	"log"
+	"mock-server/data"
+	"mock-server/workers"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// maxRecordErrors caps the per-record errors kept on a job, the rest are only counted.
+const maxRecordErrors = 100
+
+// maxJobs is how many jobs are remembered, the oldest finished ones are forgotten first.
+const maxJobs = 200
+
+// saveInterval is how often a running job saves its progress and looks for a cancel
+// requested on another replica, a var so tests can shorten it.
+var saveInterval = 5 * time.Second
+
+// Saved jobs are jobs_<id> documents, a cancel asked for on another replica a jobcancels_<id> one.
+const (
+	jobPrefix    = "jobs_"
+	cancelPrefix = "jobcancels_"
+)
+
+var (
+	ErrNotFound = errors.New("job not found")
+	ErrFinished = errors.New("job already finished")
+)
+
+type RecordError struct {
+	Id    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// Job is a snapshot of a background initialization.
+type Job struct {
+	Id            string            `json:"id"`
+	Kind          string            `json:"kind"`
+	Params        map[string]string `json:"params,omitempty"`
+	Status        Status            `json:"status"`
+	Total         int               `json:"total"`
+	Synced        int               `json:"synced"`
+	Unchanged     int               `json:"unchanged"`
+	Failed        int               `json:"failed"`
+	Errors        []RecordError     `json:"errors"`
+	ErrorsDropped int               `json:"errorsDropped,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	StartedAt     time.Time         `json:"startedAt"`
+	FinishedAt    *time.Time        `json:"finishedAt,omitempty"`
+	//CancelRequested is set once a cancel was asked for and the job hasn't stopped yet
+	CancelRequested bool `json:"cancelRequested,omitempty"`
+}
+
+func (j Job) Finished() bool {
+	return j.Status != StatusRunning
+}
+
+// Progress is handed to a running job to report its records.
+type Progress struct {
+	mu              sync.Mutex
+	job             Job
+	cancel          context.CancelFunc
+	cancelRequested bool
+}
+
+func (p *Progress) AddTotal(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.Total += n
+}
+
+func (p *Progress) Synced(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.Synced++
+}
+
+// Unchanged counts a record that was already up to date and wasn't rewritten.
+func (p *Progress) Unchanged(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.Unchanged++
+}
+
+func (p *Progress) Failed(id string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.Failed++
+	if len(p.job.Errors) < maxRecordErrors {
+		p.job.Errors = append(p.job.Errors, RecordError{Id: id, Error: err.Error()})
+	} else {
+		p.job.ErrorsDropped++
+	}
+}
+
+func (p *Progress) snapshot() Job {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job := p.job
+	job.Errors = append([]RecordError{}, p.job.Errors...)
+	return job
+}
+
+func (p *Progress) finish(err error, cancelled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.job.FinishedAt = &now
+	p.job.CancelRequested = false
+
+	switch {
+	case cancelled:
+		p.job.Status = StatusCancelled
+	case err != nil:
+		p.job.Status = StatusFailed
+	default:
+		p.job.Status = StatusSucceeded
+	}
+	if err != nil {
+		p.job.Error = err.Error()
+	}
+}
+
+// requestCancel cancels the job's context, or does so as soon as the job picks it up.
+func (p *Progress) requestCancel() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cancelRequested = true
+	p.job.CancelRequested = true
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// Registry runs jobs on the worker group and keeps their state for the jobs API.
+// With a Store, jobs are also saved as jobs_<id> documents, so every replica can
+// report and cancel the jobs of the others.
+type Registry struct {
+	Workers *workers.Group
+	//Store returns the store jobs are saved in, jobs only live in memory when nil
+	Store func() (data.DocumentStore, error)
+
+	mu   sync.Mutex
+	jobs map[string]*Progress
+}
+
+func NewRegistry(workerGroup *workers.Group) *Registry {
+	return &Registry{
+		Workers: workerGroup,
+		jobs:    map[string]*Progress{},
+	}
+}
+
+// Start runs fn in the background and returns the job right away.
+// fn should stop when its context is cancelled, which happens on Cancel or a timed out shutdown.
+func (r *Registry) Start(kind string, params map[string]string, fn func(ctx context.Context, progress *Progress) error) (Job, error) {
+	progress := &Progress{
+		job: Job{
+			Id:        uuid2.New().String(),
+			Kind:      kind,
+			Params:    params,
+			Status:    StatusRunning,
+			Errors:    []RecordError{},
+			StartedAt: time.Now(),
+		},
+	}
+
+	r.mu.Lock()
+	r.prune()
+	r.jobs[progress.job.Id] = progress
+	r.mu.Unlock()
+
+	//saved before the job runs, so this first snapshot can't overwrite a later one
+	r.save(progress.snapshot())
+
+	err := r.Workers.Go(kind+" "+progress.job.Id, func(ctx context.Context) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		progress.mu.Lock()
+		progress.cancel = cancel
+		if progress.cancelRequested {
+			cancel()
+		}
+		progress.mu.Unlock()
+
+		stopSaving := make(chan struct{})
+		savingStopped := make(chan struct{})
+		go func() {
+			defer close(savingStopped)
+			r.saveWhileRunning(progress, stopSaving)
+		}()
+
+		err := fn(ctx, progress)
+		close(stopSaving)
+		<-savingStopped
+		progress.finish(err, errors.Is(err, context.Canceled))
+
+		job := progress.snapshot()
+		r.save(job)
+		log.Println("Job", job.Id, kind, job.Status, "synced", job.Synced, "unchanged", job.Unchanged, "failed", job.Failed, "of", job.Total)
+	})
+
+	if err != nil {
+		r.mu.Lock()
+		delete(r.jobs, progress.job.Id)
+		r.mu.Unlock()
+		r.forget(progress.job.Id)
+		return Job{}, err
+	}
+
+	return progress.snapshot(), nil
+}
+
+// saveWhileRunning saves the job's progress every saveInterval until stop is closed,
+// cancelling it once another replica asked for it.
+func (r *Registry) saveWhileRunning(progress *Progress, stop chan struct{}) {
+	if r.Store == nil {
+		return
+	}
+
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		job := progress.snapshot()
+		if !job.CancelRequested && r.cancelStored(job.Id) {
+			log.Println("Job", job.Id, "was cancelled on another replica")
+			progress.requestCancel()
+			job = progress.snapshot()
+		}
+		r.save(job)
+	}
+}
+
+func (r *Registry) Get(id string) (Job, error) {
+	r.mu.Lock()
+	progress, ok := r.jobs[id]
+	r.mu.Unlock()
+
+	if ok {
+		return progress.snapshot(), nil
+	}
+	return r.load(id)
+}
+
+// List returns every remembered job, newest first, those of other replicas included.
+func (r *Registry) List() ([]Job, error) {
+	r.mu.Lock()
+	list := make([]Job, 0, len(r.jobs))
+	local := map[string]bool{}
+	for id, progress := range r.jobs {
+		list = append(list, progress.snapshot())
+		local[id] = true
+	}
+	r.mu.Unlock()
+
+	if r.Store != nil {
+		store, err := r.Store()
+		if err != nil {
+			return nil, err
+		}
+
+		documents, err := store.Query(jobPrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, document := range documents {
+			var job Job
+			err = json.Unmarshal(document.Content, &job)
+			if err != nil {
+				log.Println("Skipping job document", document.Id, err)
+				continue
+			}
+			if !local[job.Id] {
+				list = append(list, job)
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartedAt.After(list[j].StartedAt)
+	})
+	return list, nil
+}
+
+// Cancel asks a running job to stop, it is marked cancelled once its function returns.
+// A job running on another replica stops once it sees the request, within saveInterval.
+func (r *Registry) Cancel(id string) (Job, error) {
+	r.mu.Lock()
+	progress, ok := r.jobs[id]
+	r.mu.Unlock()
+
+	if !ok {
+		return r.cancelRemote(id)
+	}
+
+	job := progress.snapshot()
+	if job.Finished() {
+		return job, ErrFinished
+	}
+
+	progress.requestCancel()
+	job = progress.snapshot()
+	r.save(job)
+	return job, nil
+}
+
+func (r *Registry) cancelRemote(id string) (Job, error) {
+	job, err := r.load(id)
+	if err != nil {
+		return Job{}, err
+	}
+
+	if job.Finished() {
+		return job, ErrFinished
+	}
+
+	store, err := r.Store()
+	if err != nil {
+		return Job{}, err
+	}
+
+	//a separate document, so the running replica's progress saves can't overwrite the request
+	err = store.Upsert(cancelPrefix+id, map[string]interface{}{"id": id, "requestedAt": time.Now()})
+	if err != nil {
+		return Job{}, err
+	}
+
+	job.CancelRequested = true
+	return job, nil
+}
+
+// save stores a job snapshot, failures are only logged as the job itself goes on.
+func (r *Registry) save(job Job) {
+	if r.Store == nil {
+		return
+	}
+
+	store, err := r.Store()
+	if err == nil {
+		err = store.Upsert(jobPrefix+job.Id, job)
+	}
+	if err != nil {
+		log.Println("Couldn't save job", job.Id, err)
+	}
+}
+
+func (r *Registry) load(id string) (Job, error) {
+	if r.Store == nil {
+		return Job{}, ErrNotFound
+	}
+
+	store, err := r.Store()
+	if err != nil {
+		return Job{}, err
+	}
+
+	var job Job
+	err = store.Get(jobPrefix+id, &job)
+	if errors.Is(err, data.ErrDocumentNotFound) {
+		return Job{}, ErrNotFound
+	}
+	if err != nil {
+		return Job{}, err
+	}
+	return job, nil
+}
+
+// cancelStored reports whether another replica asked to cancel the job.
+func (r *Registry) cancelStored(id string) bool {
+	store, err := r.Store()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	var request map[string]interface{}
+	err = store.Get(cancelPrefix+id, &request)
+	if err != nil && !errors.Is(err, data.ErrDocumentNotFound) {
+		log.Println("Couldn't check job", id, "for a cancel", err)
+	}
+	return err == nil
+}
+
+// prune forgets the oldest finished jobs once more than maxJobs are kept.
+func (r *Registry) prune() {
+	if len(r.jobs) < maxJobs {
+		return
+	}
+
+	var finished []Job
+	for _, progress := range r.jobs {
+		job := progress.snapshot()
+		if job.Finished() {
+			finished = append(finished, job)
+		}
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].StartedAt.Before(finished[j].StartedAt)
+	})
+
+	for i := 0; i < len(finished) && len(r.jobs) >= maxJobs; i++ {
+		delete(r.jobs, finished[i].Id)
+		r.forget(finished[i].Id)
+	}
+}
+
+// forget removes a pruned job's documents from the store.
+func (r *Registry) forget(id string) {
+	if r.Store == nil {
+		return
+	}
+
+	store, err := r.Store()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, documentId := range []string{jobPrefix + id, cancelPrefix + id} {
+		err = store.Remove(documentId)
+		if err != nil && !errors.Is(err, data.ErrDocumentNotFound) {
+			log.Println("Couldn't forget job", id, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"mock-server/data"
+	"mock-server/workers"
+	"testing"
+	"time"
+)
+
+// newRegistry is one replica's registry, replicas given the same store share their jobs.
+func newRegistry(store data.DocumentStore) *Registry {
+	registry := NewRegistry(workers.NewGroup())
+	registry.Store = func() (data.DocumentStore, error) {
+		return store, nil
+	}
+	return registry
+}
+
+// waitFinished polls a registry until the job finished.
+func waitFinished(t *testing.T, registry *Registry, id string) Job {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := registry.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s didn't finish", id)
+	return Job{}
+}
+
+func TestJobOutcome(t *testing.T) {
+	tests := []struct {
+		name       string
+		fn         func(ctx context.Context, progress *Progress) error
+		wantStatus Status
+		wantSynced int
+		wantFailed int
+	}{
+		{
+			name: "succeeded",
+			fn: func(ctx context.Context, progress *Progress) error {
+				progress.AddTotal(3)
+				progress.Synced("clients_1")
+				progress.Unchanged("clients_2")
+				progress.Failed("clients_3", errors.New("no details"))
+				return nil
+			},
+			wantStatus: StatusSucceeded,
+			wantSynced: 1,
+			wantFailed: 1,
+		},
+		{
+			name:       "failed",
+			fn:         func(ctx context.Context, progress *Progress) error { return errors.New("fineract down") },
+			wantStatus: StatusFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := data.NewMemoryStore()
+			registry := newRegistry(store)
+
+			started, err := registry.Start("clients", map[string]string{"officeId": "1"}, test.fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			job := waitFinished(t, registry, started.Id)
+			if job.Status != test.wantStatus || job.Synced != test.wantSynced || job.Failed != test.wantFailed {
+				t.Errorf("job = %+v, want %s with %d synced and %d failed", job, test.wantStatus, test.wantSynced, test.wantFailed)
+			}
+
+			//another replica reads the final snapshot from the store
+			stored, err := newRegistry(store).Get(started.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != test.wantStatus {
+				t.Errorf("stored status = %s, want %s", stored.Status, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	saveInterval = 10 * time.Millisecond
+	defer func() { saveInterval = 5 * time.Second }()
+
+	waitForCancel := func(ctx context.Context, progress *Progress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name   string
+		remote bool
+	}{
+		{name: "on the replica running it"},
+		{name: "from another replica", remote: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := data.NewMemoryStore()
+			runner := newRegistry(store)
+			canceller := runner
+			if test.remote {
+				canceller = newRegistry(store)
+			}
+
+			started, err := runner.Start("groups", nil, waitForCancel)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			jobs, err := canceller.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(jobs) != 1 || jobs[0].Id != started.Id {
+				t.Fatalf("List = %+v, want the started job", jobs)
+			}
+
+			job, err := canceller.Cancel(started.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !job.CancelRequested {
+				t.Errorf("cancelled job = %+v, want CancelRequested", job)
+			}
+
+			job = waitFinished(t, runner, started.Id)
+			if job.Status != StatusCancelled {
+				t.Errorf("status = %s, want %s", job.Status, StatusCancelled)
+			}
+
+			_, err = canceller.Cancel(started.Id)
+			if !errors.Is(err, ErrFinished) {
+				t.Errorf("second Cancel = %v, want ErrFinished", err)
+			}
+		})
+	}
+
+	_, err := newRegistry(data.NewMemoryStore()).Cancel("missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel(missing) = %v, want ErrNotFound", err)
+	}
+}
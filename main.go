@@ -1,295 +1,664 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/joho/godotenv"
-	"io/ioutil"
+	"io"
 	"log"
 	"mock-server/auth"
 	"mock-server/cliff"
+	"mock-server/config"
 	"mock-server/data"
+	"mock-server/fakegen"
+	"mock-server/faults"
+	"mock-server/jobs"
+	"mock-server/mockcliff"
+	"mock-server/mocksgw"
+	"mock-server/reconcile"
+	"mock-server/router"
+	"mock-server/scenario"
+	"mock-server/workers"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
 type LoginRequestDTO struct {
 	JWT string `json:"jwt"`
+	//OfficeId is the office the user covers, DEFAULT_OFFICE_ID when 0
+	OfficeId int `json:"officeId"`
 }
 
 type ApiRequestDTO struct {
 	Id string `json:"id"`
 }
 
-func writeError(w http.ResponseWriter, err error, statusCode int) {
-	w.WriteHeader(statusCode)
-	_, err = w.Write([]byte(err.Error()))
-	if err != nil {
-		log.Println(err)
-		return
-	}
-	log.Println(err)
-}
-
-type APIRequestTest struct {
-	ClientEmail       string    `json:"clientEmail"`
-	CreatedAt         time.Time `json:"createdAt"`
-	Endpoint          string    `json:"endpoint"`
-	Headers           string    `json:"headers"`
-	Id                string    `json:"id"`
-	OrganizationUnits string    `json:"organizationUnits"`
-	RequestData       string    `json:"requestData"`
-	Type              string    `json:"type"`
-	Verb              string    `json:"verb"`
-	ClientMetadata    string    `json:"clientMetadata"`
-	Checksum          string    `json:"checksum"`
-}
-
-type Config struct {
-	secret            string
-	sgwBaseURL        string
-	districtId        string
-	serverPort        string
-	couchbaseURL      string
-	couchbaseReadsDB  string
-	couchbaseWritesDB string
-	couchbaseUser     string
-	couchbasePass     string
-	cliffToken        string
-	cliffBaseURL      string
-	defaultOfficeId   string
+type InitializationRequestDTO struct {
+	OfficeIds  []int `json:"officeIds"`
+	OfficeRoot int   `json:"officeRoot"`
+	//Delta only syncs what changed in Fineract since the office's last delta sync
+	Delta bool `json:"delta"`
 }
 
-//global envs map
-
 func main() {
 
-	//populate config from environment variables
-	config := Config{
-		secret:            os.Getenv("SECRET"),
-		sgwBaseURL:        os.Getenv("SGW_BASE_URL"),
-		districtId:        os.Getenv("DISTRICT_ID"),
-		serverPort:        os.Getenv("SERVER_PORT"),
-		couchbaseURL:      os.Getenv("COUCHBASE_URL"),
-		couchbaseReadsDB:  os.Getenv("COUCHBASE_READS_DB"),
-		couchbaseWritesDB: os.Getenv("COUCHBASE_WRITES_DB"),
-		couchbaseUser:     os.Getenv("COUCHBASE_USER"),
-		couchbasePass:     os.Getenv("COUCHBASE_PASS"),
-		cliffToken:        os.Getenv("CLIFF_TOKEN"),
-		cliffBaseURL:      os.Getenv("CLIFF_BASE_URL"),
-		defaultOfficeId:   os.Getenv("DEFAULT_OFFICE_ID"),
-	}
-
-	//check if all config values are set
-	if config.secret == "" || config.sgwBaseURL == "" || config.districtId == "" || config.serverPort == "" || config.couchbaseURL == "" || config.couchbaseReadsDB == "" || config.couchbaseWritesDB == "" || config.couchbaseUser == "" || config.couchbasePass == "" || config.cliffToken == "" || config.cliffBaseURL == "" || config.defaultOfficeId == "" {
-		envFiles := []string{".env", "../.env"}
-		availableEnvFile := ""
-		var envs map[string]string
-
-		for _, envFile := range envFiles {
-			//check if file exists
-			if _, err := ioutil.ReadFile(envFile); err == nil {
-				availableEnvFile = envFile
-				break
-			}
-		}
-
-		if availableEnvFile == "" {
-			log.Fatal("No env file found")
-		}
-
-		envs, err := godotenv.Read(availableEnvFile)
+	//flags before the subcommand override the config file, .env and environment
+	cfg, args, err := config.Load(os.Args[1:])
 
+	if len(args) > 0 && args[0] == "print-config" {
+		cfg.Print(os.Stdout)
 		if err != nil {
 			log.Fatal(err)
 		}
+		return
+	}
 
-		config = Config{
-			secret:            envs["SECRET"],
-			sgwBaseURL:        envs["SGW_BASE_URL"],
-			districtId:        envs["DISTRICT_ID"],
-			serverPort:        envs["SERVER_PORT"],
-			couchbaseURL:      envs["COUCHBASE_URL"],
-			couchbaseReadsDB:  envs["CB_DB"],
-			couchbaseWritesDB: envs["CB_WRITES_DB"],
-			couchbaseUser:     envs["CB_USER"],
-			couchbasePass:     envs["CB_PASS"],
-			cliffToken:        envs["CLIFF_TOKEN"],
-			cliffBaseURL:      envs["CLIFF_BASE_URL"],
-			defaultOfficeId:   envs["DEFAULT_OFFICE_ID"],
-		}
-
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	//define some constants
 	const (
 		getClientsEndpoint    = "/fineract-provider/api/v1/clients"
 		getGroupsEndpoint     = "/fineract-provider/api/v1/groups"
+		getOfficesEndpoint    = "/fineract-provider/api/v1/offices"
+		getAuditsEndpoint     = "/fineract-provider/api/v1/audits"
+		getCodesEndpoint      = "/fineract-provider/api/v1/codes"
 		createClientsEndpoint = "/fineract-provider/api/v1/clients"
 		updateClientsEndpoint = "/fineract-provider/api/v1/clients"
 	)
 
-	couchbaseService := data.NewService(config.couchbaseURL, config.couchbaseReadsDB, config.couchbaseWritesDB, config.couchbaseUser, config.couchbasePass)
-	cliffService := cliff.NewCliffService(config.cliffBaseURL, config.cliffToken, config.defaultOfficeId, getClientsEndpoint, getGroupsEndpoint, createClientsEndpoint, updateClientsEndpoint)
+	couchbaseService, err := newDataService(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	//http server
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		_, err := w.Write([]byte("Welcome to Mobile Sync Gateway Mock Server"))
+	//background work is tracked so a shutdown can drain it
+	workerGroup := workers.NewGroup()
+	couchbaseService.Workers = workerGroup
+	jobRegistry := jobs.NewRegistry(workerGroup)
+	//jobs are saved in the writes bucket so any replica can report and cancel them
+	jobRegistry.Store = func() (data.DocumentStore, error) {
+		return couchbaseService.Store("writes")
+	}
+	if cfg.CliffMode == "replay" && cfg.CliffBaseURL == "" {
+		cfg.CliffBaseURL = "http://fineract.replay"
+	}
+
+	cliffService := cliff.NewCliffService(cfg.CliffBaseURL, cfg.CliffToken, cfg.DefaultOfficeId, getClientsEndpoint, getGroupsEndpoint, getOfficesEndpoint, getAuditsEndpoint, getCodesEndpoint, createClientsEndpoint, updateClientsEndpoint)
+
+	cliffService.AddressTypeId = cfg.AddressTypeId
+	codeCache := cliff.NewCodeCache(cliffService)
+	cliffService.Codes = codeCache
+
+	cliffTransport, err := newCliffTransport(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	//faults are configured at runtime through the admin api, none are active at startup
+	faultInjector := faults.NewInjector()
+	cliffService.HTTPClient = &http.Client{Transport: &faults.Transport{Injector: faultInjector, Next: cliffTransport}}
+	//a replayed Fineract only exists on tape, otherwise readiness pings it directly
+	if cfg.CliffMode == "replay" {
+		cliffService.PingClient = &http.Client{Transport: cliffTransport}
+	}
+	couchbaseService.UseFaults(faultInjector)
+
+	//subcommands run against the configured store and Fineract and exit instead of serving,
+	//before the mocks start or a scenario is applied
+	if len(args) > 0 {
+		err = runCommand(args[0], args[1:], cfg, couchbaseService, cliffService)
+		couchbaseService.Close()
 		if err != nil {
-			return
+			log.Fatal(err)
 		}
-	})
+		return
+	}
 
-	//Webhook endpoint from Fineract that gets called whenever a client Changes
-	http.HandleFunc("/api/v3/client-updates/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			//get the body
-			body, err := ioutil.ReadAll(r.Body)
-			if err != nil {
-				log.Println(err)
-				writeError(w, err, http.StatusBadRequest)
-			}
+	var mockCliff *mockcliff.Server
+	if cfg.CliffMode == "mock" {
+		mockCliff, cfg.CliffBaseURL, err = startMockCliff(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cliffService.BaseURL = cfg.CliffBaseURL
+	}
 
-			var payload cliff.WebhookPayload
-			err = json.Unmarshal(body, &payload)
-			if err != nil {
-				log.Println(err)
-				writeError(w, err, http.StatusBadRequest)
-			}
+	var mockSGW *mocksgw.Server
+	if cfg.SGWMode == "mock" {
+		mockSGW = mocksgw.NewServer()
+		cfg.SGWBaseURL, err = mockSGW.Start("127.0.0.1:0")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
-			w.Write([]byte("OK"))
-			go updateClientFromWebhook(payload, err, cliffService, couchbaseService)
+	scenarioRunner := &scenario.Runner{
+		Data:   couchbaseService,
+		Cliff:  mockCliff,
+		SGW:    mockSGW,
+		Locale: cfg.FakeLocale,
+	}
+
+	if cfg.ScenarioFile != "" {
+		loaded, err := scenario.Load(cfg.ScenarioFile)
+		if err != nil {
+			log.Fatal(err)
 		}
+		err = scenarioRunner.Apply(loaded)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	reconcileOffices := cfg.ReconcileOffices
+	if len(reconcileOffices) == 0 {
+		reconcileOffices = []string{cfg.DefaultOfficeId}
+	}
+	reconciler := &reconcile.Scheduler{
+		Data:      couchbaseService,
+		Cliff:     cliffService,
+		Workers:   workerGroup,
+		OfficeIds: reconcileOffices,
+		Interval:  cfg.ReconcileInterval,
+	}
+
+	mux := router.New()
+	registerAdminRoutes(mux, cfg, couchbaseService, mockSGW, scenarioRunner, faultInjector, reconciler)
+	registerHealthRoutes(mux, cfg, couchbaseService, cliffService)
+
+	//http server
+	mux.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		return router.Text(w, http.StatusOK, "Welcome to Mobile Sync Gateway Mock Server")
+	})
+
+	//Webhook endpoint from Fineract that gets called whenever a client Changes,
+	//hooks configured with a suffixed url still land here
+	mux.Post("/api/v3/client-updates/{rest...}", func(w http.ResponseWriter, r *http.Request) error {
+		var payload cliff.WebhookPayload
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			return router.BadRequest(err)
+		}
+
+		err = workerGroup.Go("client update "+strconv.Itoa(payload.Response.ResourceId), func(ctx context.Context) {
+			updateClientFromWebhook(payload, nil, cliffService, couchbaseService)
+		})
+		if err != nil {
+			return router.NewError(http.StatusServiceUnavailable, "shutting_down", err)
+		}
+		return router.Text(w, http.StatusOK, "OK")
 	})
 
 	//This is supposed to be called to inititialize ALL Clients
 	//From Fineract to Couchbase
 	//This could be done everytime a login happens which is like refreshing data on demand!
-	http.HandleFunc("/api/v3/client-initializations", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			cliffClients, err := cliffService.GetOfficeClients(config.defaultOfficeId)
+	//The body picks the offices, {"officeIds": [240, 241]} or {"officeRoot": 12} for a whole subtree,
+	//DEFAULT_OFFICE_ID without one. "delta": true only syncs the clients changed since the last delta sync.
+	//It runs as a job, follow it on /api/v3/jobs/{id}
+	mux.Post("/api/v3/client-initializations", func(w http.ResponseWriter, r *http.Request) error {
+		request, officeIds, err := initializationOffices(r, cfg, cliffService, couchbaseService)
+		if err != nil {
+			return err
+		}
+
+		if request.Delta {
+			job, err := startInitialization(jobRegistry, "client-delta-sync", officeIds, func(ctx context.Context, officeId string, progress *jobs.Progress) error {
+				return couchbaseService.SyncClientsDelta(ctx, officeId, cliffService, progress)
+			})
+			return writeJob(w, job, err)
+		}
+
+		job, err := startInitialization(jobRegistry, "client-initialization", officeIds, func(ctx context.Context, officeId string, progress *jobs.Progress) error {
+			cliffClients, failed, err := cliffService.GetOfficeClients(ctx, officeId)
 			if err != nil {
-				fmt.Println(err)
-				return
+				return fmt.Errorf("fineract: %w", err)
 			}
-			//write response string
-			response := "Saving clients to couchbase"
-			w.Write([]byte(response))
-			go couchbaseService.SaveInitialClients(cliffClients)
-		}
+			progress.AddTotal(len(failed))
+			for _, failure := range failed {
+				progress.Failed("clients_"+failure.Client.AccountNo, failure.Err)
+			}
+			return couchbaseService.SaveClients(ctx, cliffClients, progress)
+		})
+		return writeJob(w, job, err)
 	})
 
-	//TODO: This is supposed to be called to inititialize ALL Groups
-	//Same as above
+	//Same as above for groups
 	//These are like refreshes in MSG
-	http.HandleFunc("/api/v3/group-initializations", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			cliffClients, err := cliffService.GetOfficeClients(config.defaultOfficeId)
+	mux.Post("/api/v3/group-initializations", func(w http.ResponseWriter, r *http.Request) error {
+		request, officeIds, err := initializationOffices(r, cfg, cliffService, couchbaseService)
+		if err != nil {
+			return err
+		}
+		if request.Delta {
+			return router.BadRequest(errors.New("delta sync is only supported for clients"))
+		}
+
+		job, err := startInitialization(jobRegistry, "group-initialization", officeIds, func(ctx context.Context, officeId string, progress *jobs.Progress) error {
+			cliffGroups, err := cliffService.GetOfficeGroups(officeId)
 			if err != nil {
-				fmt.Println(err)
-				return
+				return fmt.Errorf("fineract: %w", err)
 			}
-			//write response string
-			response := "Saving clients to couchbase"
-			w.Write([]byte(response))
-			go couchbaseService.SaveInitialClients(cliffClients)
-		}
+			return couchbaseService.SaveGroups(ctx, cliffGroups, progress)
+		})
+		return writeJob(w, job, err)
 	})
 
-	//This is a webhook that gets called whenever a user writes to Couchbase (Offline Writes)
-	http.HandleFunc("/api/v3/api-requests", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			body, err := ioutil.ReadAll(r.Body)
+	//Sync Fineract's office hierarchy into offices_<id> documents, used for login channels
+	//and office subtree initializations
+	mux.Post("/api/v3/office-initializations", func(w http.ResponseWriter, r *http.Request) error {
+		job, err := jobRegistry.Start("office-initialization", nil, func(ctx context.Context, progress *jobs.Progress) error {
+			cliffOffices, err := cliffService.GetOffices()
 			if err != nil {
-				writeError(w, err, http.StatusInternalServerError)
+				return fmt.Errorf("fineract: %w", err)
 			}
+			return couchbaseService.SaveOffices(ctx, cliffOffices, progress)
+		})
+		return writeJob(w, job, err)
+	})
 
-			var requestDTO ApiRequestDTO
-			err = json.Unmarshal(body, &requestDTO)
+	mux.Get("/api/v3/jobs", func(w http.ResponseWriter, r *http.Request) error {
+		list, err := jobRegistry.List()
+		if err != nil {
+			return err
+		}
+		return router.JSON(w, http.StatusOK, list)
+	})
 
-			if err != nil {
-				writeError(w, err, http.StatusBadRequest)
-			}
+	mux.Get("/api/v3/jobs/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		job, err := jobRegistry.Get(router.Param(r, "id"))
+		if errors.Is(err, jobs.ErrNotFound) {
+			return router.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		return router.JSON(w, http.StatusOK, job)
+	})
 
-			err = couchbaseService.ProcessApiRequest(requestDTO.Id, cliffService)
+	mux.Post("/api/v3/jobs/{id}/cancel", func(w http.ResponseWriter, r *http.Request) error {
+		job, err := jobRegistry.Cancel(router.Param(r, "id"))
+		if errors.Is(err, jobs.ErrNotFound) {
+			return router.NotFound(err)
+		}
+		if errors.Is(err, jobs.ErrFinished) {
+			return router.NewError(http.StatusConflict, "job_finished", err)
+		}
+		if err != nil {
+			return err
+		}
+		return router.JSON(w, http.StatusAccepted, job)
+	})
 
-			if err != nil {
-				writeError(w, err, http.StatusInternalServerError)
-			}
+	//This is a webhook that gets called whenever a user writes to Couchbase (Offline Writes)
+	mux.Post("/api/v3/api-requests", func(w http.ResponseWriter, r *http.Request) error {
+		var requestDTO ApiRequestDTO
+		err := json.NewDecoder(r.Body).Decode(&requestDTO)
+		if err != nil {
+			return router.BadRequest(err)
+		}
 
-			w.WriteHeader(http.StatusCreated)
-			message := fmt.Sprintf("Successfully created API request with id: %s", requestDTO.Id)
-			_, err = w.Write([]byte(message))
+		err = couchbaseService.ProcessApiRequest(requestDTO.Id, cliffService)
+		if errors.Is(err, data.ErrDocumentNotFound) {
+			return router.NotFound(fmt.Errorf("api request %s: %w", requestDTO.Id, err))
+		}
+		if errors.Is(err, workers.ErrDraining) {
+			return router.NewError(http.StatusServiceUnavailable, "shutting_down", err)
+		}
+		if err != nil {
+			return err
 		}
+
+		message := fmt.Sprintf("Successfully created API request with id: %s", requestDTO.Id)
+		return router.Text(w, http.StatusCreated, message)
 	})
 
-	http.HandleFunc("/api/v3/login", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			//get the body
-			body, err := ioutil.ReadAll(r.Body)
-			if err != nil {
-				writeError(w, err, http.StatusInternalServerError)
-				return
-			}
+	mux.Post("/api/v3/login", func(w http.ResponseWriter, r *http.Request) error {
+		var loginRequestDto LoginRequestDTO
+		err := json.NewDecoder(r.Body).Decode(&loginRequestDto)
+		if err != nil {
+			return router.BadRequest(err)
+		}
 
-			//unmarshal the body
-			var loginRequestDto LoginRequestDTO
+		generator, err := generatorFor(r, cfg)
+		if err != nil {
+			return router.BadRequest(err)
+		}
 
-			err = json.Unmarshal(body, &loginRequestDto)
+		// Parse the token
+		authService := auth.Service{
+			JWT:         loginRequestDto.JWT,
+			Secret:      cfg.Secret,
+			SGWBaseURL:  cfg.SGWBaseURL,
+			DistrictId:  cfg.DefaultOfficeId,
+			Generator:   generator,
+			MaxOrgUnits: cfg.FakeMaxOrgUnits,
+		}
 
-			if err != nil {
-				writeError(w, err, http.StatusBadRequest)
-				return
-			}
+		//grant the channels of every office below the user's, once offices are synced
+		officeId := loginRequestDto.OfficeId
+		if officeId == 0 {
+			officeId, _ = strconv.Atoi(cfg.DefaultOfficeId)
+		}
+		offices, err := couchbaseService.OfficeSubtree(officeId)
+		if err != nil && !errors.Is(err, data.ErrDocumentNotFound) {
+			return err
+		}
+		for _, office := range offices {
+			authService.OfficeIds = append(authService.OfficeIds, strconv.Itoa(office.OfficeId))
+		}
+		authService.OrgUnits = orgUnits(offices, generator)
+		if len(offices) == 0 {
+			authService.OfficeIds = []string{strconv.Itoa(officeId)}
+		}
 
-			// Parse the token
-			authService := auth.Service{
-				JWT:        loginRequestDto.JWT,
-				Secret:     config.secret,
-				SGWBaseURL: config.sgwBaseURL,
-				DistrictId: config.defaultOfficeId,
-			}
+		claims, err := authService.RetrieveClaims()
+		if err != nil {
+			return router.Unauthorized(err)
+		}
 
-			claims, err := authService.RetrieveClaims()
+		created, err := authService.CreateSGWUser(&claims)
+		if err != nil {
+			return router.NewError(http.StatusBadGateway, "sync_gateway_error", err)
+		}
 
+		return router.JSON(w, http.StatusOK, created)
+	})
+
+	server := &http.Server{Addr: ":" + cfg.ServerPort, Handler: mux}
+	reconciler.Start()
+	//requests left pending while code values or Fineract were unavailable are resent once they are back
+	codeCache.OnRefresh = func() {
+		go func() {
+			sent, err := couchbaseService.ResendPendingApiRequests(cliffService)
 			if err != nil {
-				writeError(w, err, http.StatusUnauthorized)
+				log.Println("Couldn't resend pending API requests", err)
 				return
 			}
+			if sent > 0 {
+				log.Println("Resent", sent, "pending API requests")
+			}
+		}()
+	}
+	codeCache.Start(cfg.CodeRefreshInterval)
 
-			created, err := authService.CreateSGWUser(&claims)
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- server.ListenAndServe()
+	}()
 
-			if err != nil {
-				writeError(w, err, http.StatusInternalServerError)
-				return
-			}
+	//run the server with a message
+	log.Println("Server started on port " + cfg.ServerPort)
 
-			//json marshall created
-			createdJson, err := json.Marshal(created)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 
-			if err != nil {
-				writeError(w, err, http.StatusInternalServerError)
-				return
-			}
+	select {
+	case err = <-serverErrors:
+		log.Fatal(err)
+	case received := <-signals:
+		log.Println("Received", received, "shutting down")
+	}
 
-			if err != nil {
-				log.Println(err)
-			}
+	shutdown(server, cfg, workerGroup, scenarioRunner, reconciler, codeCache, couchbaseService)
+}
+
+// shutdown stops accepting requests, lets in-flight ones finish, drains the background
+// workers they started and then closes the stores, all within SHUTDOWN_TIMEOUT.
+func shutdown(server *http.Server, cfg config.Config, workerGroup *workers.Group, scenarioRunner *scenario.Runner, reconciler *reconcile.Scheduler, codeCache *cliff.CodeCache, couchbaseService *data.Service) {
+	deadline := time.Now().Add(cfg.ShutdownTimeout)
 
-			_, err = w.Write([]byte(fmt.Sprintf("%s", createdJson)))
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		log.Println("HTTP shutdown:", err)
+	}
 
+	scenarioRunner.Stop()
+	reconciler.Stop()
+	codeCache.Stop()
+
+	err = workerGroup.Drain(time.Until(deadline))
+	if err != nil {
+		log.Println(err)
+	}
+
+	//jobs that ignored their cancellation may still write, closing the stores under them would lose those writes
+	if running := workerGroup.Running(); len(running) > 0 {
+		log.Println("Leaving the stores open for", len(running), "background jobs still running")
+		log.Println("Shutdown complete")
+		return
+	}
+
+	err = couchbaseService.Close()
+	if err != nil {
+		log.Println(err)
+	}
+
+	log.Println("Shutdown complete")
+}
+
+// newDataService picks the document store backing the reads and writes buckets
+func newDataService(cfg config.Config) (*data.Service, error) {
+	switch cfg.StoreDriver {
+	case "", "couchbase":
+		return data.NewService(cfg.CouchbaseURL, cfg.CouchbaseReadsDB, cfg.CouchbaseWritesDB, cfg.CouchbaseUser, cfg.CouchbasePass), nil
+	case "memory":
+		log.Println("Using in-memory document store, data will not survive a restart")
+		return data.NewServiceWithStores(data.NewMemoryStore(), data.NewMemoryStore()), nil
+	case "file":
+		storeDir := cfg.StoreDir
+		reads, err := data.OpenFileStore(storeDir, "reads")
+		if err != nil {
+			return nil, err
+		}
+		writes, err := data.OpenFileStore(storeDir, "writes")
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Using file document store in", storeDir)
+		return data.NewServiceWithStores(reads, writes), nil
+	default:
+		return nil, fmt.Errorf("unknown store driver %q", cfg.StoreDriver)
+	}
+}
+
+// startMockCliff runs the embedded fake Fineract and returns the base url cliff.Service should use.
+// It posts its webhooks back to this server, closing the init -> offline write -> webhook loop locally.
+func startMockCliff(cfg config.Config) (*mockcliff.Server, string, error) {
+	mockServer := mockcliff.NewServer()
+	mockServer.WebhookURL = "http://localhost:" + cfg.ServerPort + "/api/v3/client-updates/"
+
+	if cfg.CliffFixture != "" {
+		err := mockServer.LoadFixture(cfg.CliffFixture)
+		if err != nil {
+			return nil, "", err
+		}
+	} else {
+		officeId, err := strconv.Atoi(cfg.DefaultOfficeId)
+		if err != nil {
+			return nil, "", fmt.Errorf("DEFAULT_OFFICE_ID must be numeric to seed the mock Fineract: %w", err)
+		}
+		generator, err := newGenerator(cfg, cfg.FakeSeed)
+		if err != nil {
+			return nil, "", err
+		}
+		mockServer.Seed(generator, officeId, cfg.FakeClients, cfg.FakeGroups)
+	}
+
+	baseURL, err := mockServer.Start("127.0.0.1:0")
+	return mockServer, baseURL, err
+}
+
+// newCliffTransport records Fineract traffic to CLIFF_TAPE_DIR with CLIFF_MODE=record,
+// and serves it back without a network with CLIFF_MODE=replay
+func newCliffTransport(cfg config.Config) (http.RoundTripper, error) {
+	tapeDir := cfg.CliffTapeDir
+
+	switch cfg.CliffMode {
+	case "record":
+		log.Println("Recording Fineract traffic to", tapeDir)
+		return &cliff.RecordingTransport{Dir: tapeDir, Next: http.DefaultTransport}, nil
+	case "replay":
+		return cliff.LoadTape(tapeDir)
+	default:
+		return http.DefaultTransport, nil
+	}
+}
+
+// newGenerator builds a fake data generator for a seed, 0 meaning a random one
+func newGenerator(cfg config.Config, seed int64) (*fakegen.Generator, error) {
+	generator, err := fakegen.New(seed, cfg.FakeLocale)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Generating", generator.Locale.Country, "fake data with seed", generator.Seed)
+	return generator, nil
+}
+
+// generatorFor lets a request pin its fake data with ?seed=, falling back to FAKE_SEED
+func generatorFor(r *http.Request, cfg config.Config) (*fakegen.Generator, error) {
+	seed := cfg.FakeSeed
+	if query := r.URL.Query().Get("seed"); query != "" {
+		var err error
+		seed, err = strconv.ParseInt(query, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fake data seed %q", query)
+		}
+	}
+	return newGenerator(cfg, seed)
+}
+
+// initializationOffices resolves the offices an initialization covers from its optional body
+func initializationOffices(r *http.Request, cfg config.Config, cliffService *cliff.Service, couchbaseService *data.Service) (InitializationRequestDTO, []string, error) {
+	var request InitializationRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return request, nil, router.BadRequest(err)
+	}
+
+	if len(request.OfficeIds) > 0 && request.OfficeRoot != 0 {
+		return request, nil, router.BadRequest(errors.New("officeIds and officeRoot are mutually exclusive"))
+	}
+
+	if request.OfficeRoot != 0 {
+		subtree, err := officeSubtree(request.OfficeRoot, cliffService, couchbaseService)
+		if err != nil {
+			return request, nil, err
+		}
+		request.OfficeIds = subtree
+	}
+
+	if len(request.OfficeIds) == 0 {
+		return request, []string{cfg.DefaultOfficeId}, nil
+	}
+
+	var officeIds []string
+	for _, officeId := range request.OfficeIds {
+		if officeId < 1 {
+			return request, nil, router.BadRequest(fmt.Errorf("invalid office id %d", officeId))
+		}
+		officeIds = append(officeIds, strconv.Itoa(officeId))
+	}
+	return request, officeIds, nil
+}
+
+// officeSubtree resolves the office ids below rootId from the synced offices,
+// asking Fineract when the hierarchy hasn't been synced yet
+func officeSubtree(rootId int, cliffService *cliff.Service, couchbaseService *data.Service) ([]int, error) {
+	var officeIds []int
+
+	stored, err := couchbaseService.OfficeSubtree(rootId)
+	if err == nil {
+		for _, office := range stored {
+			officeIds = append(officeIds, office.OfficeId)
+		}
+		return officeIds, nil
+	}
+	if !errors.Is(err, data.ErrDocumentNotFound) {
+		return nil, err
+	}
+
+	offices, err := cliffService.GetOffices()
+	if err != nil {
+		return nil, router.NewError(http.StatusBadGateway, "fineract_error", err)
+	}
+	subtree, err := cliff.OfficeSubtree(offices, rootId)
+	if err != nil {
+		return nil, router.NotFound(err)
+	}
+	for _, office := range subtree {
+		officeIds = append(officeIds, office.Id)
+	}
+	return officeIds, nil
+}
+
+// orgUnits turns synced offices into the geographic info returned on login,
+// naming levels by their depth in the hierarchy
+func orgUnits(offices []data.Office, generator *fakegen.Generator) []auth.OU {
+	levelNames := generator.Locale.LevelNames
+
+	var units []auth.OU
+	for _, office := range offices {
+		depth := strings.Count(strings.Trim(office.Hierarchy, "."), ".")
+		if strings.Trim(office.Hierarchy, ".") != "" {
+			depth++
+		}
+		if depth >= len(levelNames) {
+			depth = len(levelNames) - 1
+		}
+
+		units = append(units, auth.OU{
+			Id:        office.OfficeId,
+			Name:      office.Name,
+			Parent:    office.ParentId,
+			LevelName: levelNames[depth],
+			IsCountry: office.ParentId == 0,
+		})
+	}
+	return units
+}
+
+// startInitialization runs sync for every office in one job. An office Fineract fails on
+// is recorded as a failed record and the others still sync.
+func startInitialization(jobRegistry *jobs.Registry, kind string, officeIds []string, sync func(ctx context.Context, officeId string, progress *jobs.Progress) error) (jobs.Job, error) {
+	params := map[string]string{"officeIds": strings.Join(officeIds, ",")}
+
+	return jobRegistry.Start(kind, params, func(ctx context.Context, progress *jobs.Progress) error {
+		for _, officeId := range officeIds {
+			err := sync(ctx, officeId, progress)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if err != nil {
-				writeError(w, err, http.StatusInternalServerError)
-				return
+				progress.Failed("offices_"+officeId, err)
 			}
 		}
+		return nil
 	})
+}
 
-	//run the server with a message
-	log.Println("Server started on port " + config.serverPort)
-	log.Fatal(http.ListenAndServe(":"+config.serverPort, nil))
+// writeJob answers 202 with the started job and where to follow it
+func writeJob(w http.ResponseWriter, job jobs.Job, err error) error {
+	if errors.Is(err, workers.ErrDraining) {
+		return router.NewError(http.StatusServiceUnavailable, "shutting_down", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", "/api/v3/jobs/"+job.Id)
+	return router.JSON(w, http.StatusAccepted, job)
 }
 
 func updateClientFromWebhook(payload cliff.WebhookPayload, err error, cliffService *cliff.Service, couchbaseService *data.Service) {
@@ -0,0 +1,121 @@
+package mockcliff
+
+import (
+	"fmt"
+	"mock-server/cliff"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const codesPath = "/fineract-provider/api/v1/codes"
+
+// mockCode is a code table of the mock tenant, value ids are unique across codes like in Fineract.
+type mockCode struct {
+	cliff.Code
+	values []cliff.CodeValue
+}
+
+var codes = []mockCode{
+	{cliff.Code{Id: 1, Name: cliff.CodeAddressType, SystemDefined: true}, codeValues(1, "Home", "Work")},
+	{cliff.Code{Id: 4, Name: cliff.CodeGender, SystemDefined: true}, codeValues(11, "Male", "Female")},
+	{cliff.Code{Id: 16, Name: cliff.CodeClientType, SystemDefined: true}, codeValues(21, "Individual", "Cooperative")},
+	{cliff.Code{Id: 17, Name: cliff.CodeClientClassification, SystemDefined: true}, codeValues(31, "Farmer", "Trader", "Employee")},
+	{cliff.Code{Id: 1001, Name: cliff.CodeDocumentType, SystemDefined: true}, codeValues(41, "National ID", "Passport", "Voter Card")},
+}
+
+func codeValues(firstId int, names ...string) []cliff.CodeValue {
+	var values []cliff.CodeValue
+	for i, name := range names {
+		values = append(values, cliff.CodeValue{Id: firstId + i, Name: name, Position: i + 1, Active: true})
+	}
+	return values
+}
+
+// codeValue finds a value of the named code, false when the id isn't one of its values.
+func codeValue(code string, id int) (cliff.CodeValue, bool) {
+	for _, mock := range codes {
+		if mock.Name != code {
+			continue
+		}
+		for _, value := range mock.values {
+			if value.Id == id {
+				return value, true
+			}
+		}
+	}
+	return cliff.CodeValue{}, false
+}
+
+// codeValueNamed finds a value of the named code by its name.
+func codeValueNamed(code string, name string) (cliff.CodeValue, bool) {
+	for _, mock := range codes {
+		if mock.Name != code {
+			continue
+		}
+		for _, value := range mock.values {
+			if value.Name == name {
+				return value, true
+			}
+		}
+	}
+	return cliff.CodeValue{}, false
+}
+
+// checkCodeValue is the validation Fineract does on a code value id of a request, 0 means none.
+func checkCodeValue(field string, code string, id int) error {
+	if id == 0 {
+		return nil
+	}
+	if _, ok := codeValue(code, id); !ok {
+		return fmt.Errorf("%s %d is not a %s code value", field, id, code)
+	}
+	return nil
+}
+
+// genderId is the Gender code value of the M or F the fake data generator picks.
+func genderId(gender string) int {
+	for _, mock := range codes {
+		if mock.Name != cliff.CodeGender {
+			continue
+		}
+		for _, value := range mock.values {
+			if strings.HasPrefix(value.Name, gender) {
+				return value.Id
+			}
+		}
+	}
+	return 0
+}
+
+// handleCodes serves the code tables and, on /codes/{id}/codevalues, the values of one.
+func (s *Server) handleCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeFineractError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, codesPath), "/")
+	if path == "" {
+		var list []cliff.Code
+		for _, mock := range codes {
+			list = append(list, mock.Code)
+		}
+		writeJSON(w, http.StatusOK, list)
+		return
+	}
+
+	codeId, err := strconv.Atoi(strings.TrimSuffix(path, "/codevalues"))
+	if err != nil || !strings.HasSuffix(path, "/codevalues") {
+		writeFineractError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	for _, mock := range codes {
+		if mock.Id == codeId {
+			writeJSON(w, http.StatusOK, mock.values)
+			return
+		}
+	}
+	writeFineractError(w, http.StatusNotFound, fmt.Sprintf("Code with identifier %d does not exist", codeId))
+}
@@ -0,0 +1,144 @@
+package mockcliff
+
+import (
+	"encoding/json"
+	"fmt"
+	"mock-server/cliff"
+	"mock-server/shared"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// newIdentifier stores a requested identifier the way the identifiers endpoint returns it, callers hold s.mu.
+func (s *Server) newIdentifier(clientId int, requested shared.ClientIdentifier) shared.IdentifierDTO {
+	identifier := shared.IdentifierDTO{Id: s.allocateDetailId(), ClientId: clientId}
+	setIdentifier(&identifier, requested)
+	return identifier
+}
+
+func setIdentifier(identifier *shared.IdentifierDTO, requested shared.ClientIdentifier) {
+	documentType, _ := codeValue(cliff.CodeDocumentType, requested.DocumentTypeId)
+	identifier.DocumentType.Id = documentType.Id
+	identifier.DocumentType.Name = documentType.Name
+	identifier.DocumentKey = requested.DocumentKey
+	identifier.Description = requested.Description
+	identifier.Status = "clientIdentifierStatusType." + strings.ToLower(requested.Status)
+}
+
+// checkIdentifier is the validation Fineract does on an identifier, including that no other
+// identifier of the tenant has the same document type and key. Callers hold s.mu.
+func (s *Server) checkIdentifier(requested shared.ClientIdentifier, exceptId int) error {
+	if requested.DocumentKey == "" {
+		return fmt.Errorf("documentKey is required")
+	}
+	if _, ok := codeValue(cliff.CodeDocumentType, requested.DocumentTypeId); !ok {
+		return fmt.Errorf("documentTypeId %d is not a %s code value", requested.DocumentTypeId, cliff.CodeDocumentType)
+	}
+	if requested.Status != "ACTIVE" && requested.Status != "INACTIVE" {
+		return fmt.Errorf("status %q is not ACTIVE or INACTIVE", requested.Status)
+	}
+
+	for _, client := range s.clients {
+		for _, identifier := range client.Identifiers {
+			if identifier.Id != exceptId && identifier.DocumentType.Id == requested.DocumentTypeId && identifier.DocumentKey == requested.DocumentKey {
+				return fmt.Errorf("an identifier of type %d with key %s already exists", requested.DocumentTypeId, requested.DocumentKey)
+			}
+		}
+	}
+	return nil
+}
+
+// handleClientIdentifiers serves /clients/{id}/identifiers and /clients/{id}/identifiers/{identifierId}.
+func (s *Server) handleClientIdentifiers(w http.ResponseWriter, r *http.Request, client *shared.ClientDTO, identifierPath string) {
+	if identifierPath == "" {
+		switch r.Method {
+		case "GET":
+			s.mu.Lock()
+			identifiers := append([]shared.IdentifierDTO{}, client.Identifiers...)
+			s.mu.Unlock()
+			writeJSON(w, http.StatusOK, identifiers)
+		case "POST":
+			var body shared.ClientIdentifier
+			err := json.NewDecoder(r.Body).Decode(&body)
+			if err != nil {
+				writeFineractError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			s.mu.Lock()
+			err = s.checkIdentifier(body, 0)
+			if err != nil {
+				s.mu.Unlock()
+				writeFineractError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			identifier := s.newIdentifier(client.Id, body)
+			client.Identifiers = append(client.Identifiers, identifier)
+			s.auditEntity("CREATE", "CLIENTIDENTIFIER", identifier.Id, *client, r)
+			s.mu.Unlock()
+
+			writeJSON(w, http.StatusOK, map[string]int{"clientId": client.Id, "resourceId": identifier.Id})
+		default:
+			writeFineractError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	identifierId, err := strconv.Atoi(identifierPath)
+	if err != nil {
+		writeFineractError(w, http.StatusNotFound, "identifier not found")
+		return
+	}
+
+	s.mu.Lock()
+	index := -1
+	for i, identifier := range client.Identifiers {
+		if identifier.Id == identifierId {
+			index = i
+		}
+	}
+	s.mu.Unlock()
+
+	if index < 0 {
+		writeFineractError(w, http.StatusNotFound, fmt.Sprintf("Client identifier with id %d does not exist", identifierId))
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		s.mu.Lock()
+		identifier := client.Identifiers[index]
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, identifier)
+	case "PUT":
+		var body shared.ClientIdentifier
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		err = s.checkIdentifier(body, identifierId)
+		if err != nil {
+			s.mu.Unlock()
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		setIdentifier(&client.Identifiers[index], body)
+		s.auditEntity("UPDATE", "CLIENTIDENTIFIER", identifierId, *client, r)
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]int{"clientId": client.Id, "resourceId": identifierId})
+	case "DELETE":
+		s.mu.Lock()
+		client.Identifiers = append(client.Identifiers[:index], client.Identifiers[index+1:]...)
+		s.auditEntity("DELETE", "CLIENTIDENTIFIER", identifierId, *client, r)
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]int{"clientId": client.Id, "resourceId": identifierId})
+	default:
+		writeFineractError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
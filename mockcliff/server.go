@@ -0,0 +1,858 @@
+package mockcliff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mock-server/cliff"
+	"mock-server/dates"
+	"mock-server/fakegen"
+	"mock-server/mockrules"
+	"mock-server/shared"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	clientsPath = "/fineract-provider/api/v1/clients"
+	groupsPath  = "/fineract-provider/api/v1/groups"
+	officesPath = "/fineract-provider/api/v1/offices"
+	auditsPath  = "/fineract-provider/api/v1/audits"
+
+	headOfficeId = 1
+
+	defaultPageSize = 200
+)
+
+// Server is an in-process stand-in for the Fineract endpoints cliff.Service talks to.
+type Server struct {
+	//WebhookURL receives a client webhook after every create and update, like Fineract hooks do
+	WebhookURL string
+	//Rules override responses, e.g. a 403 on client creation for one officer
+	Rules mockrules.Set
+
+	mu      sync.Mutex
+	clients map[int]*shared.ClientDTO
+	groups  map[int]*shared.GroupDTO
+	offices map[int]*shared.OfficeDTO
+	audits  []audit
+	nextId  int
+	//nextDetailId numbers addresses and identifiers so client ids stay consecutive
+	nextDetailId int
+}
+
+// audit is an audit trail entry with what the audits endpoint filters on.
+type audit struct {
+	shared.AuditDTO
+	officeId int
+	madeOn   time.Time
+}
+
+// Fixture is the file format accepted by LoadFixture.
+type Fixture struct {
+	Clients []shared.ClientDTO `json:"clients"`
+	Groups  []shared.GroupDTO  `json:"groups"`
+	Offices []shared.OfficeDTO `json:"offices"`
+}
+
+func NewServer() *Server {
+	return &Server{
+		clients:      map[int]*shared.ClientDTO{},
+		groups:       map[int]*shared.GroupDTO{},
+		offices:      headOffice(),
+		nextId:       1,
+		nextDetailId: 1,
+	}
+}
+
+// headOffice is the root every Fineract tenant starts with.
+func headOffice() map[int]*shared.OfficeDTO {
+	return map[int]*shared.OfficeDTO{
+		headOfficeId: {Id: headOfficeId, Name: "Head Office", NameDecorated: "Head Office", Hierarchy: "."},
+	}
+}
+
+func (s *Server) LoadFixture(path string) error {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	var fixture Fixture
+	err = json.Unmarshal(content, &fixture)
+
+	if err != nil {
+		return err
+	}
+
+	s.AddFixture(fixture)
+	log.Println("Loaded", len(fixture.Clients), "clients and", len(fixture.Groups), "groups from", path)
+	return nil
+}
+
+func (s *Server) AddFixture(fixture Fixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range fixture.Offices {
+		office := fixture.Offices[i]
+		s.offices[office.Id] = &office
+	}
+
+	for i := range fixture.Clients {
+		client := fixture.Clients[i]
+		s.clients[client.Id] = &client
+		s.ensureOffice(client.OfficeId)
+		if client.Id >= s.nextId {
+			s.nextId = client.Id + 1
+		}
+		for _, address := range client.Addresses {
+			if address.AddressId >= s.nextDetailId {
+				s.nextDetailId = address.AddressId + 1
+			}
+		}
+		for _, identifier := range client.Identifiers {
+			if identifier.Id >= s.nextDetailId {
+				s.nextDetailId = identifier.Id + 1
+			}
+		}
+	}
+
+	for i := range fixture.Groups {
+		group := fixture.Groups[i]
+		s.groups[group.Id] = &group
+		s.ensureOffice(group.OfficeId)
+		if group.Id >= s.nextId {
+			s.nextId = group.Id + 1
+		}
+	}
+}
+
+// Reset drops every client, group and office but the head office.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clients = map[int]*shared.ClientDTO{}
+	s.groups = map[int]*shared.GroupDTO{}
+	s.offices = headOffice()
+	s.audits = nil
+	s.nextId = 1
+	s.nextDetailId = 1
+}
+
+// Seed generates fake active clients and groups for an office.
+func (s *Server) Seed(generator *fakegen.Generator, officeId int, numClients int, numGroups int) {
+	s.SeedGroups(generator, officeId, numGroups)
+	s.SeedClients(generator, officeId, numClients, true)
+	log.Println("Seeded", numClients, "clients and", numGroups, "groups for office", officeId)
+}
+
+func (s *Server) SeedGroups(generator *fakegen.Generator, officeId int, numGroups int) []shared.GroupDTO {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := generator.Reference
+	today := dates.ToFineract(now)
+	officeName := s.ensureOffice(officeId).Name
+
+	var groups []shared.GroupDTO
+	for i := 0; i < numGroups; i++ {
+		id := s.allocateId()
+		s.groups[id] = &shared.GroupDTO{
+			Id:             id,
+			AccountNo:      fmt.Sprintf("%09d", id),
+			Name:           generator.LastName() + " Group",
+			Status:         shared.GroupStatus{Id: 300, Code: "clientStatusType.active", Value: "Active"},
+			ActivationDate: today,
+			Active:         true,
+			OfficeId:       officeId,
+			OfficeName:     officeName,
+			Hierarchy:      s.offices[officeId].Hierarchy,
+			Configurations: shared.GroupConfiguration{MaxClientsInGroup: 30},
+		}
+		groups = append(groups, *s.groups[id])
+	}
+	return groups
+}
+
+func (s *Server) SeedClients(generator *fakegen.Generator, officeId int, numClients int, active bool) []shared.ClientDTO {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := generator.Reference
+	today := dates.ToFineract(now)
+	officeName := s.ensureOffice(officeId).Name
+
+	var clients []shared.ClientDTO
+	for i := 0; i < numClients; i++ {
+		id := s.allocateId()
+		firstname, gender := generator.Person()
+		dob := generator.Date(now.AddDate(-70, 0, 0), now.AddDate(-18, 0, 0))
+		client := shared.ClientDTO{
+			Id:             id,
+			AccountNo:      fmt.Sprintf("%09d", id),
+			ExternalId:     generator.NationalId(),
+			Active:         active,
+			ActivationDate: today,
+			Firstname:      firstname,
+			Lastname:       generator.LastName(),
+			MobileNo:       generator.PhoneNumber(),
+			DateOfBirth:    dates.ToFineract(dob),
+			OfficeId:       officeId,
+			OfficeName:     officeName,
+		}
+		setCodeValues(&client, genderId(gender), 0, 0)
+		nationalId, _ := codeValueNamed(cliff.CodeDocumentType, "National ID")
+		client.Identifiers = []shared.IdentifierDTO{s.newIdentifier(id, shared.ClientIdentifier{
+			DocumentTypeId: nationalId.Id,
+			DocumentKey:    client.ExternalId,
+			Status:         "ACTIVE",
+		})}
+		client.DisplayName = client.Firstname + " " + client.Lastname
+		latitude, longitude := generator.Coordinates()
+		client.Addresses = []shared.AddressDTO{{
+			AddressId:     s.allocateDetailId(),
+			AddressTypeId: 1,
+			AddressType:   "Home",
+			IsActive:      true,
+			City:          generator.Place(),
+			Latitude:      float64(latitude),
+			Longitude:     float64(longitude),
+		}}
+		client.Status.Id = 300
+		client.Status.Code = "clientStatusType.active"
+		client.Status.Value = "Active"
+		if !active {
+			client.ActivationDate = nil
+			client.Status.Id = 100
+			client.Status.Code = "clientStatusType.pending"
+			client.Status.Value = "Pending"
+		}
+		s.clients[id] = &client
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// ensureOffice adds officeId under the head office unless it exists, callers hold s.mu.
+func (s *Server) ensureOffice(officeId int) *shared.OfficeDTO {
+	office, ok := s.offices[officeId]
+	if ok {
+		return office
+	}
+
+	office = &shared.OfficeDTO{
+		Id:            officeId,
+		Name:          fmt.Sprintf("Office %d", officeId),
+		NameDecorated: fmt.Sprintf("....Office %d", officeId),
+		Hierarchy:     fmt.Sprintf(".%d.", officeId),
+		ParentId:      headOfficeId,
+		ParentName:    s.offices[headOfficeId].Name,
+	}
+	s.offices[officeId] = office
+	return office
+}
+
+func (s *Server) allocateId() int {
+	id := s.nextId
+	s.nextId += 1
+	return id
+}
+
+func (s *Server) allocateDetailId() int {
+	id := s.nextDetailId
+	s.nextDetailId += 1
+	return id
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(clientsPath, s.handleClients)
+	mux.HandleFunc(clientsPath+"/", s.handleClient)
+	mux.HandleFunc(groupsPath, s.handleGroups)
+	mux.HandleFunc(officesPath, s.handleOffices)
+	mux.HandleFunc(auditsPath, s.handleAudits)
+	mux.HandleFunc(codesPath, s.handleCodes)
+	mux.HandleFunc(codesPath+"/", s.handleCodes)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Rules.Respond(w, r) {
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Start serves the fake Fineract on addr and returns its base URL.
+func (s *Server) Start(addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		err := http.Serve(listener, s.Handler())
+		if err != nil {
+			log.Println("Mock Fineract stopped", err)
+		}
+	}()
+
+	baseURL := "http://" + listener.Addr().String()
+	log.Println("Mock Fineract listening on", baseURL)
+	return baseURL, nil
+}
+
+func (s *Server) handleClients(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.mu.Lock()
+		var clients []shared.ClientDTO
+		officeId := r.URL.Query().Get("officeId")
+		for _, client := range s.clients {
+			if officeId == "" || strconv.Itoa(client.OfficeId) == officeId {
+				clients = append(clients, withoutDetails(*client))
+			}
+		}
+		s.mu.Unlock()
+
+		sort.Slice(clients, func(i, j int) bool {
+			return clients[i].Id < clients[j].Id
+		})
+
+		writeJSON(w, http.StatusOK, shared.ClientsResponseDTO{
+			TotalFilteredRecords: len(clients),
+			PageItems:            page(r, clients),
+		})
+	case "POST":
+		var body shared.CreateClientDTO
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		activationDate, err := parseFineractDate(body.ActivationDate, body.DateFormat, body.Locale)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, "activationDate: "+err.Error())
+			return
+		}
+
+		dateOfBirth, err := parseFineractDate(body.DateOfBirth, body.DateFormat, body.Locale)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, "dateOfBirth: "+err.Error())
+			return
+		}
+
+		err = checkClientCodes(body)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		for _, identifier := range body.Identifiers {
+			err = s.checkIdentifier(identifier, 0)
+			if err != nil {
+				break
+			}
+		}
+		s.mu.Unlock()
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		id := s.allocateId()
+		client := shared.ClientDTO{
+			Id:             id,
+			AccountNo:      fmt.Sprintf("%09d", id),
+			Active:         body.Active,
+			ActivationDate: activationDate,
+			Firstname:      body.Firstname,
+			Lastname:       body.Lastname,
+			DisplayName:    body.Firstname + " " + body.Lastname,
+			MobileNo:       body.MobileNo,
+			DateOfBirth:    dateOfBirth,
+			OfficeId:       body.OfficeId,
+			OfficeName:     s.ensureOffice(body.OfficeId).Name,
+		}
+		client.LegalForm.Id = body.LegalFormId
+		client.LegalForm.Value = cliff.LegalForms[body.LegalFormId]
+		setCodeValues(&client, body.GenderId, body.ClientTypeId, body.ClientClassificationId)
+		for _, address := range body.Address {
+			address.AddressId = s.allocateDetailId()
+			addressType, _ := codeValue(cliff.CodeAddressType, address.AddressTypeId)
+			address.AddressType = addressType.Name
+			client.Addresses = append(client.Addresses, address)
+		}
+		for _, identifier := range body.Identifiers {
+			client.Identifiers = append(client.Identifiers, s.newIdentifier(id, identifier))
+		}
+		s.clients[id] = &client
+		s.audit("CREATE", client, r)
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, shared.CreateClientResponse{
+			OfficeId:   client.OfficeId,
+			ClientId:   id,
+			ResourceId: id,
+			AccountNo:  client.AccountNo,
+		})
+		go s.SendWebhook("CREATE", id)
+	default:
+		writeFineractError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleClient(w http.ResponseWriter, r *http.Request) {
+	//{id}, {id}/addresses, {id}/identifiers or {id}/identifiers/{identifierId}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, clientsPath+"/"), "/", 3)
+
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		writeFineractError(w, http.StatusNotFound, "client not found")
+		return
+	}
+
+	s.mu.Lock()
+	client, ok := s.clients[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeFineractError(w, http.StatusNotFound, fmt.Sprintf("Client with identifier %d does not exist", id))
+		return
+	}
+
+	if len(parts) > 1 {
+		switch {
+		case parts[1] == "addresses" && len(parts) == 2:
+			s.handleClientAddresses(w, r, client)
+		case parts[1] == "identifiers" && len(parts) == 2:
+			s.handleClientIdentifiers(w, r, client, "")
+		case parts[1] == "identifiers":
+			s.handleClientIdentifiers(w, r, client, parts[2])
+		default:
+			writeFineractError(w, http.StatusNotFound, "not found")
+		}
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		s.mu.Lock()
+		current := withoutDetails(*client)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, current)
+	case "PUT":
+		var body shared.ClientUpdateBody
+		err = json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		activationDate, err := parseFineractDate(body.ActivationDate, body.DateFormat, body.Locale)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, "activationDate: "+err.Error())
+			return
+		}
+
+		dateOfBirth, err := parseFineractDate(body.DateOfBirth, body.DateFormat, body.Locale)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, "dateOfBirth: "+err.Error())
+			return
+		}
+
+		for _, err := range []error{
+			checkCodeValue("genderId", cliff.CodeGender, body.GenderId),
+			checkCodeValue("clientTypeId", cliff.CodeClientType, body.ClientTypeId),
+			checkCodeValue("clientClassificationId", cliff.CodeClientClassification, body.ClientClassificationId),
+		} {
+			if err != nil {
+				writeFineractError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		s.mu.Lock()
+		if activationDate != nil {
+			client.ActivationDate = activationDate
+		}
+		if dateOfBirth != nil {
+			client.DateOfBirth = dateOfBirth
+		}
+		setCodeValues(client, body.GenderId, body.ClientTypeId, body.ClientClassificationId)
+		if body.Firstname != "" {
+			client.Firstname = body.Firstname
+		}
+		if body.Lastname != "" {
+			client.Lastname = body.Lastname
+		}
+		if body.MobileNo != "" {
+			client.MobileNo = body.MobileNo
+		}
+		client.DisplayName = client.Firstname + " " + client.Lastname
+		s.audit("UPDATE", *client, r)
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, shared.CreateClientResponse{
+			OfficeId:   client.OfficeId,
+			ClientId:   id,
+			ResourceId: id,
+			AccountNo:  client.AccountNo,
+		})
+		go s.SendWebhook("UPDATE", id)
+	default:
+		writeFineractError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// checkClientCodes rejects a new client with a legal form or code value id Fineract doesn't know.
+func checkClientCodes(body shared.CreateClientDTO) error {
+	if _, ok := cliff.LegalForms[body.LegalFormId]; !ok {
+		return fmt.Errorf("legalFormId %d is not a legal form", body.LegalFormId)
+	}
+
+	checks := []error{
+		checkCodeValue("genderId", cliff.CodeGender, body.GenderId),
+		checkCodeValue("clientTypeId", cliff.CodeClientType, body.ClientTypeId),
+		checkCodeValue("clientClassificationId", cliff.CodeClientClassification, body.ClientClassificationId),
+	}
+	for _, address := range body.Address {
+		if address.AddressTypeId == 0 {
+			return fmt.Errorf("addressTypeId is required")
+		}
+		checks = append(checks, checkCodeValue("addressTypeId", cliff.CodeAddressType, address.AddressTypeId))
+	}
+
+	for _, err := range checks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCodeValues sets the code values of a client that were given, ids already checked.
+func setCodeValues(client *shared.ClientDTO, genderId int, clientTypeId int, clientClassificationId int) {
+	if gender, ok := codeValue(cliff.CodeGender, genderId); ok {
+		client.Gender.Id = gender.Id
+		client.Gender.Name = gender.Name
+	}
+	if clientType, ok := codeValue(cliff.CodeClientType, clientTypeId); ok {
+		client.ClientType.Id = clientType.Id
+		client.ClientType.Name = clientType.Name
+	}
+	if classification, ok := codeValue(cliff.CodeClientClassification, clientClassificationId); ok {
+		client.ClientClassification.Id = classification.Id
+		client.ClientClassification.Name = classification.Name
+	}
+}
+
+// handleClientAddresses lists, adds and updates the addresses of a client like Fineract's address module.
+// A new address takes its type from the type parameter, an update names its address in addressId
+// and only changes the fields it sends.
+func (s *Server) handleClientAddresses(w http.ResponseWriter, r *http.Request, client *shared.ClientDTO) {
+	switch r.Method {
+	case "GET":
+		s.mu.Lock()
+		addresses := append([]shared.AddressDTO{}, client.Addresses...)
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, addresses)
+	case "POST":
+		addressTypeId, err := strconv.Atoi(r.URL.Query().Get("type"))
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, "type is required")
+			return
+		}
+		addressType, ok := codeValue(cliff.CodeAddressType, addressTypeId)
+		if !ok {
+			writeFineractError(w, http.StatusBadRequest, fmt.Sprintf("type %d is not an address type", addressTypeId))
+			return
+		}
+
+		var address shared.AddressDTO
+		err = json.NewDecoder(r.Body).Decode(&address)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		address.AddressId = s.allocateDetailId()
+		address.AddressTypeId = addressTypeId
+		address.AddressType = addressType.Name
+		client.Addresses = append(client.Addresses, address)
+		s.auditEntity("CREATE", "ADDRESS", address.AddressId, *client, r)
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]int{"clientId": client.Id, "resourceId": address.AddressId})
+	case "PUT":
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var update struct {
+			AddressId int `json:"addressId"`
+		}
+		err = json.Unmarshal(body, &update)
+		if err != nil || update.AddressId == 0 {
+			writeFineractError(w, http.StatusBadRequest, "addressId is required")
+			return
+		}
+
+		s.mu.Lock()
+		index := -1
+		for i, address := range client.Addresses {
+			if address.AddressId == update.AddressId {
+				index = i
+			}
+		}
+		if index >= 0 {
+			updated := client.Addresses[index]
+			err = json.Unmarshal(body, &updated)
+			if err == nil {
+				updated.AddressTypeId = client.Addresses[index].AddressTypeId
+				updated.AddressType = client.Addresses[index].AddressType
+				client.Addresses[index] = updated
+				s.auditEntity("UPDATE", "ADDRESS", update.AddressId, *client, r)
+			}
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if index >= 0 {
+			writeJSON(w, http.StatusOK, map[string]int{"clientId": client.Id, "resourceId": update.AddressId})
+			return
+		}
+		writeFineractError(w, http.StatusNotFound, fmt.Sprintf("Address with id %d does not exist", update.AddressId))
+	default:
+		writeFineractError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// withoutDetails is a client as Fineract returns it, addresses and identifiers have their own endpoints.
+func withoutDetails(client shared.ClientDTO) shared.ClientDTO {
+	client.Addresses = nil
+	client.Identifiers = nil
+	return client
+}
+
+func (s *Server) handleGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeFineractError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.mu.Lock()
+	var groups []shared.GroupDTO
+	officeId := r.URL.Query().Get("officeId")
+	for _, group := range s.groups {
+		if officeId == "" || strconv.Itoa(group.OfficeId) == officeId {
+			groups = append(groups, *group)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Id < groups[j].Id
+	})
+
+	//like Fineract the groups list is only paged on request
+	if r.URL.Query().Get("paged") != "true" {
+		writeJSON(w, http.StatusOK, groups)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		TotalFilteredRecords int               `json:"totalFilteredRecords"`
+		PageItems            []shared.GroupDTO `json:"pageItems"`
+	}{len(groups), page(r, groups)})
+}
+
+func (s *Server) handleOffices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeFineractError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.mu.Lock()
+	var offices []shared.OfficeDTO
+	for _, office := range s.offices {
+		offices = append(offices, *office)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(offices, func(i, j int) bool {
+		return offices[i].Id < offices[j].Id
+	})
+
+	writeJSON(w, http.StatusOK, offices)
+}
+
+// audit records a processed client command, callers hold s.mu.
+func (s *Server) audit(action string, client shared.ClientDTO, r *http.Request) {
+	s.auditEntity(action, "CLIENT", client.Id, client, r)
+}
+
+// auditEntity records a processed command on an entity of a client, e.g. one of its identifiers.
+// Callers hold s.mu.
+func (s *Server) auditEntity(action string, entityName string, resourceId int, client shared.ClientDTO, r *http.Request) {
+	s.audits = append(s.audits, audit{
+		AuditDTO: shared.AuditDTO{
+			Id:               len(s.audits) + 1,
+			ActionName:       action,
+			EntityName:       entityName,
+			ResourceId:       resourceId,
+			ClientId:         client.Id,
+			OfficeName:       client.OfficeName,
+			Maker:            r.Header.Get(cliff.OfficerHeader),
+			ProcessingResult: "processed",
+		},
+		officeId: client.OfficeId,
+		madeOn:   time.Now(),
+	})
+}
+
+// handleAudits serves the paged audit trail filtered like Fineract does on
+// entityName, actionName, officeId and makerDateTimeFrom.
+func (s *Server) handleAudits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeFineractError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	var from time.Time
+	if value := query.Get("makerDateTimeFrom"); value != "" {
+		var err error
+		from, err = time.Parse("2006-01-02 15:04:05", value)
+		if err != nil {
+			writeFineractError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	s.mu.Lock()
+	var audits []shared.AuditDTO
+	for _, entry := range s.audits {
+		if entityName := query.Get("entityName"); entityName != "" && entry.EntityName != entityName {
+			continue
+		}
+		if actionName := query.Get("actionName"); actionName != "" && entry.ActionName != actionName {
+			continue
+		}
+		if officeId := query.Get("officeId"); officeId != "" && strconv.Itoa(entry.officeId) != officeId {
+			continue
+		}
+		if entry.madeOn.Before(from) {
+			continue
+		}
+		audits = append(audits, entry.AuditDTO)
+	}
+	s.mu.Unlock()
+
+	if query.Get("paged") != "true" {
+		writeJSON(w, http.StatusOK, audits)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		TotalFilteredRecords int               `json:"totalFilteredRecords"`
+		PageItems            []shared.AuditDTO `json:"pageItems"`
+	}{len(audits), page(r, audits)})
+}
+
+// SendWebhook posts a client webhook for clientId to WebhookURL.
+func (s *Server) SendWebhook(action string, clientId int) {
+	if s.WebhookURL == "" {
+		return
+	}
+
+	payload := cliff.WebhookPayload{
+		EntityName: "CLIENT",
+		ActionName: action,
+		Response:   cliff.WebhookResponse{ResourceId: clientId},
+		Timestamp:  time.Now(),
+	}
+
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(s.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Println("Mock Fineract webhook failed", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// page applies Fineract's offset and limit query parameters
+func page[T any](r *http.Request, items []T) []T {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	if offset >= len(items) {
+		return []T{}
+	}
+
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
+
+// parseFineractDate turns a request date in its dateFormat and locale into Fineract's [yyyy, m, d] array,
+// nil when there's no date. Like Fineract, a date needs a dateFormat and a locale.
+func parseFineractDate(value string, dateFormat string, locale string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if dateFormat == "" || locale == "" {
+		return nil, errors.New("dateFormat and locale are required with a date")
+	}
+
+	date, err := dates.Parse(value, dateFormat, locale)
+	if err != nil {
+		return nil, err
+	}
+	return dates.ToFineract(date), nil
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func writeFineractError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"httpStatusCode":               strconv.Itoa(statusCode),
+		"defaultUserMessage":           message,
+		"developerMessage":             message,
+		"userMessageGlobalisationCode": "error.msg.mock.fineract",
+	})
+}
@@ -0,0 +1,224 @@
+package mockcliff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mock-server/cliff"
+	"mock-server/fakegen"
+	"mock-server/shared"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestService serves a mock Fineract seeded with fake data and returns a cliff.Service pointed at it.
+func newTestService(t *testing.T) (*Server, *cliff.Service) {
+	server := NewServer()
+	generator, err := fakegen.New(1, fakegen.DefaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Seed(generator, 1, 3, 3)
+
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	service := cliff.NewCliffService(httpServer.URL, "token", "1", clientsPath, groupsPath, officesPath, auditsPath, codesPath, clientsPath, clientsPath)
+	service.AddressTypeId = 1
+	service.Codes = cliff.NewCodeCache(service)
+	err = service.Codes.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, service
+}
+
+func TestGroupsPaging(t *testing.T) {
+	server, service := newTestService(t)
+
+	tests := []struct {
+		name  string
+		query string
+		paged bool
+	}{
+		{name: "plain array", query: "?officeId=1"},
+		{name: "page envelope", query: "?officeId=1&paged=true", paged: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", groupsPath+test.query, nil))
+
+			var groups []shared.GroupDTO
+			if test.paged {
+				var envelope struct {
+					PageItems []shared.GroupDTO `json:"pageItems"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &envelope)
+				if err != nil {
+					t.Fatal(err)
+				}
+				groups = envelope.PageItems
+			} else {
+				err := json.Unmarshal(recorder.Body.Bytes(), &groups)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(groups) != 3 {
+				t.Errorf("groups = %d, want 3: %s", len(groups), recorder.Body)
+			}
+		})
+	}
+
+	groups, err := service.GetOfficeGroups("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 {
+		t.Errorf("GetOfficeGroups = %d groups, want 3", len(groups))
+	}
+}
+
+func TestUpdateClientAddress(t *testing.T) {
+	server, service := newTestService(t)
+
+	created, err, _ := service.UpsertClient(shared.ParsedClientRequestBody{
+		ClientBio: shared.ClientBodyBio{Firstname: "Ada", Lastname: "Lovelace", Active: true, LegalForm: "PERSON"},
+	}, "POST", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientId := fmt.Sprint(created.ClientId)
+
+	tests := []struct {
+		name    string
+		address shared.ClientBodyAddress
+		want    []shared.AddressDTO
+		calls   int
+	}{
+		{
+			name:    "adds an address",
+			address: shared.ClientBodyAddress{Street: "Main", City: "Kakamega", PostalCode: 50100},
+			want:    []shared.AddressDTO{{AddressTypeId: 1, Street: "Main", City: "Kakamega", PostalCode: "50100"}},
+			calls:   1,
+		},
+		{
+			name:    "updates the address of the same type",
+			address: shared.ClientBodyAddress{Street: "Market", City: "Kakamega", PostalCode: 50100},
+			want:    []shared.AddressDTO{{AddressTypeId: 1, Street: "Market", City: "Kakamega", PostalCode: "50100"}},
+			calls:   1,
+		},
+		{
+			name:    "skips an address the client has",
+			address: shared.ClientBodyAddress{Street: "Market", City: "Kakamega", PostalCode: 50100},
+			want:    []shared.AddressDTO{{AddressTypeId: 1, Street: "Market", City: "Kakamega", PostalCode: "50100"}},
+		},
+		{
+			name:    "adds an address of another type",
+			address: shared.ClientBodyAddress{AddressTypeId: 2, Street: "Office"},
+			want: []shared.AddressDTO{
+				{AddressTypeId: 1, Street: "Market", City: "Kakamega", PostalCode: "50100"},
+				{AddressTypeId: 2, Street: "Office"},
+			},
+			calls: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := len(server.auditsOf("ADDRESS"))
+
+			_, err, statusCode := service.UpdateClient(shared.ParsedClientRequestBody{
+				FineractClientId: created.ClientId,
+				ClientBio:        shared.ClientBodyBio{Firstname: "Ada"},
+				ClientAddress:    test.address,
+			}, "")
+			if err != nil {
+				t.Fatalf("UpdateClient: %v (%d)", err, statusCode)
+			}
+
+			addresses, err := service.GetClientAddresses(clientId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(addresses) != len(test.want) {
+				t.Fatalf("addresses = %+v, want %+v", addresses, test.want)
+			}
+			for i, address := range addresses {
+				want := test.want[i]
+				if address.AddressTypeId != want.AddressTypeId || address.Street != want.Street || address.City != want.City || address.PostalCode != want.PostalCode {
+					t.Errorf("address %d = %+v, want %+v", i, address, want)
+				}
+			}
+
+			if calls := len(server.auditsOf("ADDRESS")) - before; calls != test.calls {
+				t.Errorf("address changes = %d, want %d", calls, test.calls)
+			}
+		})
+	}
+}
+
+// auditsOf lists the audits recorded for an entity.
+func (s *Server) auditsOf(entityName string) []shared.AuditDTO {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var audits []shared.AuditDTO
+	for _, entry := range s.audits {
+		if entry.EntityName == entityName {
+			audits = append(audits, entry.AuditDTO)
+		}
+	}
+	return audits
+}
+
+func TestClients(t *testing.T) {
+	server, service := newTestService(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantItems  int
+	}{
+		{name: "office clients", path: clientsPath + "?officeId=1", wantStatus: 200, wantItems: 3},
+		{name: "page", path: clientsPath + "?officeId=1&offset=1&limit=1", wantStatus: 200, wantItems: 1},
+		{name: "past the end", path: clientsPath + "?officeId=1&offset=5", wantStatus: 200, wantItems: 0},
+		{name: "other office", path: clientsPath + "?officeId=2", wantStatus: 200, wantItems: 0},
+		{name: "unknown client", path: clientsPath + "/999", wantStatus: 404},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+			if test.wantStatus != 200 {
+				return
+			}
+
+			var response shared.ClientsResponseDTO
+			err := json.Unmarshal(recorder.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(response.PageItems) != test.wantItems {
+				t.Errorf("page items = %d, want %d", len(response.PageItems), test.wantItems)
+			}
+		})
+	}
+
+	clients, failed, err := service.GetOfficeClients(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 3 || len(failed) != 0 {
+		t.Errorf("GetOfficeClients = %d clients and %d failures, want 3 and 0", len(clients), len(failed))
+	}
+}
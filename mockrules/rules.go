@@ -0,0 +1,79 @@
+package mockrules
+
+import (
+	"encoding/json"
+	"mock-server/cliff"
+	"net/http"
+	"path"
+	"sync"
+)
+
+// OfficerHeader carries the email of the officer a request is made for,
+// so rules can target a single officer.
+const OfficerHeader = cliff.OfficerHeader
+
+// Rule overrides the response of the requests it matches.
+type Rule struct {
+	Method string `json:"method"`
+	//Path is a path.Match pattern, e.g. /fineract-provider/api/v1/clients/*
+	Path       string          `json:"path"`
+	Officer    string          `json:"officer"`
+	StatusCode int             `json:"statusCode"`
+	Body       json.RawMessage `json:"body"`
+	//Times limits how often the rule fires, 0 means always
+	Times int `json:"times"`
+	fired int
+}
+
+// Set is a concurrency safe list of rules, first match wins.
+type Set struct {
+	mu    sync.Mutex
+	rules []*Rule
+}
+
+func (s *Set) Replace(rules []Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules = nil
+	for i := range rules {
+		rule := rules[i]
+		s.rules = append(s.rules, &rule)
+	}
+}
+
+// Respond writes the response of the first rule matching r and reports whether one did.
+func (s *Set) Respond(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	rule := s.match(r)
+	s.mu.Unlock()
+
+	if rule == nil {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(rule.StatusCode)
+	_, _ = w.Write(rule.Body)
+	return true
+}
+
+func (s *Set) match(r *http.Request) *Rule {
+	for _, rule := range s.rules {
+		if rule.Times > 0 && rule.fired >= rule.Times {
+			continue
+		}
+		if rule.Method != "" && rule.Method != r.Method {
+			continue
+		}
+		if rule.Officer != "" && rule.Officer != r.Header.Get(OfficerHeader) {
+			continue
+		}
+		if matched, _ := path.Match(rule.Path, r.URL.Path); rule.Path != "" && !matched {
+			continue
+		}
+		rule.fired += 1
+		return rule
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package mockrules
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRespond(t *testing.T) {
+	type request struct {
+		method  string
+		path    string
+		officer string
+		want    int
+	}
+
+	tests := []struct {
+		name     string
+		rules    []Rule
+		requests []request
+	}{
+		{
+			name:  "times limits the rule",
+			rules: []Rule{{Path: "/clients", StatusCode: 500, Times: 2}},
+			requests: []request{
+				{method: "GET", path: "/clients", want: 500},
+				{method: "GET", path: "/clients", want: 500},
+				{method: "GET", path: "/clients", want: 0},
+			},
+		},
+		{
+			name:  "zero times always fires",
+			rules: []Rule{{Path: "/clients", StatusCode: 503}},
+			requests: []request{
+				{method: "GET", path: "/clients", want: 503},
+				{method: "GET", path: "/clients", want: 503},
+				{method: "GET", path: "/clients", want: 503},
+			},
+		},
+		{
+			name:  "officer only matches their requests",
+			rules: []Rule{{Officer: "ada@example.org", StatusCode: 403}},
+			requests: []request{
+				{method: "POST", path: "/clients", officer: "grace@example.org", want: 0},
+				{method: "POST", path: "/clients", want: 0},
+				{method: "POST", path: "/clients", officer: "ada@example.org", want: 403},
+			},
+		},
+		{
+			name:  "times only counts matching requests",
+			rules: []Rule{{Officer: "ada@example.org", StatusCode: 403, Times: 1}},
+			requests: []request{
+				{method: "POST", path: "/clients", officer: "grace@example.org", want: 0},
+				{method: "POST", path: "/clients", officer: "ada@example.org", want: 403},
+				{method: "POST", path: "/clients", officer: "ada@example.org", want: 0},
+			},
+		},
+		{
+			name: "first match wins, then the next rule once exhausted",
+			rules: []Rule{
+				{Method: "POST", Path: "/clients/*", StatusCode: 500, Times: 1},
+				{Path: "/clients/*", StatusCode: 404},
+			},
+			requests: []request{
+				{method: "GET", path: "/clients/1", want: 404},
+				{method: "POST", path: "/clients/1", want: 500},
+				{method: "POST", path: "/clients/1", want: 404},
+				{method: "POST", path: "/groups/1", want: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var set Set
+			set.Replace(test.rules)
+
+			for i, request := range test.requests {
+				r := httptest.NewRequest(request.method, request.path, nil)
+				if request.officer != "" {
+					r.Header.Set(OfficerHeader, request.officer)
+				}
+				recorder := httptest.NewRecorder()
+
+				responded := set.Respond(recorder, r)
+
+				status := 0
+				if responded {
+					status = recorder.Code
+				}
+				if status != request.want {
+					t.Errorf("request %d %s %s = %d, want %d", i, request.method, request.path, status, request.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package mocksgw
+
+import (
+	"encoding/json"
+	uuid2 "github.com/google/uuid"
+	"io/ioutil"
+	"log"
+	"mock-server/mockrules"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server fakes the Sync Gateway admin API endpoints auth.Service uses and records every call.
+type Server struct {
+	//Rules override responses, e.g. failing user creation on one database
+	Rules mockrules.Set
+
+	mu    sync.Mutex
+	users map[string]map[string]User
+	calls []Call
+}
+
+type User struct {
+	Name          string   `json:"name"`
+	Password      string   `json:"password,omitempty"`
+	AdminChannels []string `json:"admin_channels"`
+	AllChannels   []string `json:"all_channels"`
+	Disabled      bool     `json:"disabled"`
+	AdminRoles    []string `json:"admin_roles"`
+	Roles         []string `json:"roles"`
+}
+
+type Session struct {
+	SessionId  string    `json:"session_id"`
+	Expires    time.Time `json:"expires"`
+	CookieName string    `json:"cookie_name"`
+}
+
+// Call is one request received by the fake admin API.
+type Call struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Body       string    `json:"body"`
+	StatusCode int       `json:"statusCode"`
+}
+
+func NewServer() *Server {
+	return &Server{
+		users: map[string]map[string]User{},
+	}
+}
+
+// Reset drops every user and recorded call.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = map[string]map[string]User{}
+	s.calls = nil
+}
+
+// Calls returns a copy of every call recorded so far.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Call{}, s.calls...)
+}
+
+// Users returns the users provisioned in a database.
+func (s *Server) Users(db string) []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var users []User
+	for _, user := range s.users[db] {
+		users = append(users, user)
+	}
+	return users
+}
+
+// Start serves the fake admin API on addr and returns its base URL.
+func (s *Server) Start(addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		err := http.Serve(listener, s)
+		if err != nil {
+			log.Println("Mock Sync Gateway stopped", err)
+		}
+	}()
+
+	baseURL := "http://" + listener.Addr().String()
+	log.Println("Mock Sync Gateway admin API listening on", baseURL)
+	return baseURL, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+
+	if !s.Rules.Respond(recorder, r) {
+		s.route(recorder, r, body)
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, Call{
+		Time:       time.Now(),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Body:       string(body),
+		StatusCode: recorder.statusCode,
+	})
+	s.mu.Unlock()
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request, body []byte) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.URL.Path == "/":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"ADMIN":   true,
+			"couchdb": "Welcome",
+			"vendor":  map[string]string{"name": "Couchbase Sync Gateway", "version": "3.0"},
+			"version": "Couchbase Sync Gateway/3.0.0(mock)",
+		})
+	case len(segments) == 2 && segments[1] == "_user":
+		s.handleUsers(w, r, segments[0], body)
+	case len(segments) == 3 && segments[1] == "_user":
+		s.handleUser(w, r, segments[0], segments[2], body)
+	case len(segments) == 2 && segments[1] == "_session":
+		s.handleSession(w, r, segments[0], body)
+	default:
+		writeSGWError(w, http.StatusNotFound, "not_found", "unknown URL")
+	}
+}
+
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request, db string, body []byte) {
+	switch r.Method {
+	case "GET":
+		s.mu.Lock()
+		var names []string
+		for name := range s.users[db] {
+			names = append(names, name)
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, names)
+	case "POST":
+		var user User
+		err := json.Unmarshal(body, &user)
+		if err != nil || user.Name == "" {
+			writeSGWError(w, http.StatusBadRequest, "Bad Request", "invalid user")
+			return
+		}
+		//like Sync Gateway only PUT replaces an existing user
+		if !s.createUser(db, user) {
+			writeSGWError(w, http.StatusConflict, "Conflict", "User already exists")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	default:
+		writeSGWError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	}
+}
+
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, db string, name string, body []byte) {
+	s.mu.Lock()
+	user, ok := s.users[db][name]
+	s.mu.Unlock()
+
+	switch r.Method {
+	case "GET":
+		if !ok {
+			writeSGWError(w, http.StatusNotFound, "not_found", "missing")
+			return
+		}
+		user.Password = ""
+		writeJSON(w, http.StatusOK, user)
+	case "PUT":
+		var update User
+		err := json.Unmarshal(body, &update)
+		if err != nil {
+			writeSGWError(w, http.StatusBadRequest, "Bad Request", "invalid user")
+			return
+		}
+		update.Name = name
+		if !s.putUser(db, update) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case "DELETE":
+		if !ok {
+			writeSGWError(w, http.StatusNotFound, "not_found", "missing")
+			return
+		}
+		s.mu.Lock()
+		delete(s.users[db], name)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	default:
+		writeSGWError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	}
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request, db string, body []byte) {
+	if r.Method != "POST" {
+		writeSGWError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+
+	var request struct {
+		Name string `json:"name"`
+		TTL  int    `json:"ttl"`
+	}
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		writeSGWError(w, http.StatusBadRequest, "Bad Request", "invalid session request")
+		return
+	}
+
+	s.mu.Lock()
+	_, ok := s.users[db][request.Name]
+	s.mu.Unlock()
+
+	if !ok {
+		writeSGWError(w, http.StatusNotFound, "not_found", "missing")
+		return
+	}
+
+	ttl := request.TTL
+	if ttl <= 0 {
+		ttl = 24 * 60 * 60
+	}
+
+	writeJSON(w, http.StatusOK, Session{
+		SessionId:  strings.Replace(uuid2.New().String(), "-", "", -1),
+		Expires:    time.Now().Add(time.Duration(ttl) * time.Second),
+		CookieName: "SyncGatewaySession",
+	})
+}
+
+// putUser creates or replaces a user, true when it was created.
+// Like Sync Gateway a replacement without a password keeps the current one.
+func (s *Server) putUser(db string, user User) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.users[db][user.Name]
+	if ok && user.Password == "" {
+		user.Password = current.Password
+	}
+	if s.users[db] == nil {
+		s.users[db] = map[string]User{}
+	}
+	s.users[db][user.Name] = user
+	return !ok
+}
+
+// createUser saves a user unless one with the same name exists.
+func (s *Server) createUser(db string, user User) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[db][user.Name]; ok {
+		return false
+	}
+	if s.users[db] == nil {
+		s.users[db] = map[string]User{}
+	}
+	s.users[db][user.Name] = user
+	return true
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func writeSGWError(w http.ResponseWriter, statusCode int, error string, reason string) {
+	writeJSON(w, statusCode, map[string]string{
+		"error":  error,
+		"reason": reason,
+	})
+}
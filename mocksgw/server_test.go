@@ -0,0 +1,64 @@
+package mocksgw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUserUpsert(t *testing.T) {
+	server := NewServer()
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		wantStatus   int
+		wantPassword string
+		wantChannels int
+	}{
+		{name: "post creates", method: "POST", path: "/db/_user/", body: `{"name":"ada","password":"one","admin_channels":["a"]}`, wantStatus: 201, wantPassword: "one", wantChannels: 1},
+		{name: "post conflicts", method: "POST", path: "/db/_user/", body: `{"name":"ada","password":"two"}`, wantStatus: 409, wantPassword: "one", wantChannels: 1},
+		{name: "put replaces", method: "PUT", path: "/db/_user/ada", body: `{"password":"two","admin_channels":["a","b"]}`, wantStatus: 200, wantPassword: "two", wantChannels: 2},
+		{name: "put without password keeps it", method: "PUT", path: "/db/_user/ada", body: `{"admin_channels":["c"]}`, wantStatus: 200, wantPassword: "two", wantChannels: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+
+			users := server.Users("db")
+			if len(users) != 1 {
+				t.Fatalf("users = %v, want one", users)
+			}
+			if users[0].Password != test.wantPassword || len(users[0].AdminChannels) != test.wantChannels {
+				t.Errorf("user = %+v, want password %q and %d channels", users[0], test.wantPassword, test.wantChannels)
+			}
+		})
+	}
+}
+
+func TestPutCreatesUser(t *testing.T) {
+	server := NewServer()
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("PUT", "/db/_user/grace", strings.NewReader(`{"password":"p"}`)))
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusCreated)
+	}
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("POST", "/db/_session", strings.NewReader(`{"name":"grace"}`)))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("session status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+}
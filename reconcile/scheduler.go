@@ -0,0 +1,183 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"mock-server/cliff"
+	"mock-server/data"
+	"mock-server/workers"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxReports is how many reconciliation reports are kept for the admin API.
+const maxReports = 10
+
+// reportPrefix is the id prefix of the reports saved in the writes bucket, where every replica reads them.
+const reportPrefix = "reconciliations_"
+
+var ErrRunning = errors.New("a reconciliation is already running")
+
+// Scheduler periodically reconciles the reads bucket with Fineract for a set of offices,
+// safety net for missed webhooks. Runs go through the worker group so shutdown drains them.
+// Every replica schedules runs, a lease lets only one of them run per Interval.
+type Scheduler struct {
+	Data      *data.Service
+	Cliff     *cliff.Service
+	Workers   *workers.Group
+	OfficeIds []string
+	Interval  time.Duration
+
+	mu      sync.Mutex
+	running bool
+	stop    chan struct{}
+}
+
+// Start runs a repairing reconciliation every Interval until Stop, a zero Interval disables it.
+func (s *Scheduler) Start() {
+	if s.Interval <= 0 {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	ticker := time.NewTicker(s.Interval)
+	log.Println("Reconciling offices", s.OfficeIds, "every", s.Interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				//the lease expires just before the next tick, whichever replica ticks first then runs
+				acquired, err := s.Data.AcquireLease("reconciliation", s.Interval*9/10)
+				if err == nil && !acquired {
+					log.Println("Skipping scheduled reconciliation, another replica runs it")
+					continue
+				}
+				if err == nil {
+					err = s.Run(true)
+				}
+				if err != nil {
+					log.Println("Skipping scheduled reconciliation:", err)
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the schedule, a run in progress is left to the worker group drain.
+func (s *Scheduler) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// Run starts a reconciliation in the background unless one is already running.
+func (s *Scheduler) Run(repair bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return ErrRunning
+	}
+
+	err := s.Workers.Go("reconciliation", func(ctx context.Context) {
+		report, err := s.Data.Reconcile(ctx, s.OfficeIds, s.Cliff, repair)
+		if err != nil {
+			log.Println("Reconciliation failed:", err)
+		} else {
+			log.Println("Reconciled", len(report.Offices), "offices,", report.Drifted(), "differences found")
+		}
+
+		if err == nil {
+			s.save(report)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.running = false
+	})
+
+	if err != nil {
+		return err
+	}
+
+	s.running = true
+	return nil
+}
+
+// Reports returns the latest reports of every replica, newest first.
+func (s *Scheduler) Reports() ([]data.ReconcileReport, error) {
+	store, err := s.Data.Store("writes")
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := store.Query(reportPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []data.ReconcileReport{}
+	for _, document := range documents {
+		var report data.ReconcileReport
+		err = json.Unmarshal(document.Content, &report)
+		if err != nil {
+			log.Println("Skipping reconciliation report", document.Id, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].StartedAt.After(reports[j].StartedAt)
+	})
+	if len(reports) > maxReports {
+		reports = reports[:maxReports]
+	}
+	return reports, nil
+}
+
+// save stores a report and removes the ones beyond maxReports.
+func (s *Scheduler) save(report data.ReconcileReport) {
+	store, err := s.Data.Store("writes")
+	if err == nil {
+		err = store.Upsert(reportPrefix+strconv.FormatInt(report.StartedAt.UnixNano(), 10), report)
+	}
+	if err != nil {
+		log.Println("Couldn't save reconciliation report", err)
+		return
+	}
+
+	documents, err := store.Query(reportPrefix)
+	if err != nil {
+		log.Println("Couldn't prune reconciliation reports", err)
+		return
+	}
+
+	//ids are start times of the same length, so sorting them sorts the reports oldest first
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].Id < documents[j].Id
+	})
+	for i := 0; i < len(documents)-maxReports; i++ {
+		err = store.Remove(documents[i].Id)
+		if err != nil && !errors.Is(err, data.ErrDocumentNotFound) {
+			log.Println("Couldn't prune reconciliation report", documents[i].Id, err)
+		}
+	}
+}
+
+func (s *Scheduler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.running
+}
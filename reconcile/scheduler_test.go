@@ -0,0 +1,53 @@
+package reconcile
+
+import (
+	"mock-server/data"
+	"testing"
+	"time"
+)
+
+func TestReportsAcrossReplicas(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		runs      int
+		wantCount int
+	}{
+		{name: "one run", runs: 1, wantCount: 1},
+		{name: "kept up to maxReports", runs: maxReports + 2, wantCount: maxReports},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shared := data.NewServiceWithStores(data.NewMemoryStore(), data.NewMemoryStore())
+			//runs happen on one replica, the admin API is answered by another
+			runner := &Scheduler{Data: shared}
+			reader := &Scheduler{Data: shared}
+
+			for i := 0; i < test.runs; i++ {
+				runner.save(data.ReconcileReport{StartedAt: start.Add(time.Duration(i) * time.Hour)})
+			}
+
+			reports, err := reader.Reports()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reports) != test.wantCount {
+				t.Fatalf("reports = %d, want %d", len(reports), test.wantCount)
+			}
+			newest := start.Add(time.Duration(test.runs-1) * time.Hour)
+			if !reports[0].StartedAt.Equal(newest) {
+				t.Errorf("newest report started at %v, want %v", reports[0].StartedAt, newest)
+			}
+
+			stored, err := shared.Writes.Query(reportPrefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stored) != test.wantCount {
+				t.Errorf("stored reports = %d, want %d", len(stored), test.wantCount)
+			}
+		})
+	}
+}
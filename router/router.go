@@ -0,0 +1,218 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	uuid2 "github.com/google/uuid"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HandlerFunc handles a request and returns an error instead of writing one,
+// so a handler cannot keep running after it failed.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Error is the JSON error envelope every failed request answers with.
+type Error struct {
+	Status    int    `json:"-"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestId string `json:"requestId"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewError(status int, code string, err error) *Error {
+	return &Error{Status: status, Code: code, Message: err.Error()}
+}
+
+func BadRequest(err error) *Error {
+	return NewError(http.StatusBadRequest, "bad_request", err)
+}
+
+func NotFound(err error) *Error {
+	return NewError(http.StatusNotFound, "not_found", err)
+}
+
+func Unauthorized(err error) *Error {
+	return NewError(http.StatusUnauthorized, "unauthorized", err)
+}
+
+type contextKey string
+
+const (
+	paramsKey    contextKey = "params"
+	requestIdKey contextKey = "requestId"
+
+	RequestIdHeader = "X-Request-Id"
+)
+
+type route struct {
+	method   string
+	segments []string
+	handler  HandlerFunc
+}
+
+// Router matches method and path, with {name} segments captured as params.
+// A trailing {name...} segment matches the rest of the path, including nothing.
+type Router struct {
+	routes []route
+}
+
+func New() *Router {
+	return &Router{}
+}
+
+func (rt *Router) Handle(method string, pattern string, handler HandlerFunc) {
+	rt.routes = append(rt.routes, route{method: method, segments: split(pattern), handler: handler})
+}
+
+func (rt *Router) Get(pattern string, handler HandlerFunc) {
+	rt.Handle(http.MethodGet, pattern, handler)
+}
+
+func (rt *Router) Post(pattern string, handler HandlerFunc) {
+	rt.Handle(http.MethodPost, pattern, handler)
+}
+
+func (rt *Router) Delete(pattern string, handler HandlerFunc) {
+	rt.Handle(http.MethodDelete, pattern, handler)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestId := r.Header.Get(RequestIdHeader)
+	if requestId == "" {
+		requestId = uuid2.New().String()
+	}
+	w.Header().Set(RequestIdHeader, requestId)
+	ctx := context.WithValue(r.Context(), requestIdKey, requestId)
+
+	segments := split(r.URL.Path)
+	var allowed []string
+
+	for _, candidate := range rt.routes {
+		params, ok := match(candidate.segments, segments)
+		if !ok {
+			continue
+		}
+
+		if candidate.method != r.Method {
+			allowed = append(allowed, candidate.method)
+			continue
+		}
+
+		r = r.WithContext(context.WithValue(ctx, paramsKey, params))
+		err := candidate.handler(w, r)
+		if err != nil {
+			WriteError(w, r, err)
+		}
+		return
+	}
+
+	r = r.WithContext(ctx)
+
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		WriteError(w, r, NewError(http.StatusMethodNotAllowed, "method_not_allowed", errors.New(r.Method+" is not allowed on "+r.URL.Path)))
+		return
+	}
+
+	WriteError(w, r, NotFound(errors.New("no route for "+r.URL.Path)))
+}
+
+// Param returns a {name} path segment captured for the request.
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey).(map[string]string)
+	return params[name]
+}
+
+func RequestId(r *http.Request) string {
+	requestId, _ := r.Context().Value(requestIdKey).(string)
+	return requestId
+}
+
+// WriteError answers with the error envelope, errors that aren't *Error become 500s.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiError *Error
+	if !errors.As(err, &apiError) {
+		apiError = NewError(http.StatusInternalServerError, "internal_error", err)
+	}
+	apiError.RequestId = RequestId(r)
+
+	log.Println(apiError.RequestId, r.Method, r.URL.Path, apiError.Status, apiError.Message)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiError.Status)
+	err = json.NewEncoder(w).Encode(map[string]*Error{"error": apiError})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// JSON writes value as a JSON response.
+func JSON(w http.ResponseWriter, status int, value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(content)
+	if err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+// Text writes a plain text response.
+func Text(w http.ResponseWriter, status int, text string) error {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := w.Write([]byte(text))
+	if err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func match(pattern []string, segments []string) (map[string]string, bool) {
+	params := map[string]string{}
+
+	if n := len(pattern); n > 0 && strings.HasPrefix(pattern[n-1], "{") && strings.HasSuffix(pattern[n-1], "...}") {
+		if len(segments) < n-1 {
+			return nil, false
+		}
+		params[pattern[n-1][1:len(pattern[n-1])-4]] = strings.Join(segments[n-1:], "/")
+		pattern, segments = pattern[:n-1], segments[:n-1]
+	}
+
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+
+	for i, segment := range pattern {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorResponses(t *testing.T) {
+	rt := New()
+	rt.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		switch Param(r, "id") {
+		case "missing":
+			return NotFound(errors.New("no item missing"))
+		case "bad":
+			return BadRequest(errors.New("bad id"))
+		case "conflict":
+			return fmt.Errorf("saving: %w", NewError(http.StatusConflict, "conflict", errors.New("item changed")))
+		case "broken":
+			return errors.New("store down")
+		}
+		return Text(w, http.StatusOK, Param(r, "id"))
+	})
+	rt.Delete("/items/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+		wantAllow   string
+	}{
+		{name: "handler not found", method: "GET", path: "/items/missing", wantStatus: 404, wantCode: "not_found", wantMessage: "no item missing"},
+		{name: "bad request", method: "GET", path: "/items/bad", wantStatus: 400, wantCode: "bad_request", wantMessage: "bad id"},
+		{name: "wrapped error", method: "GET", path: "/items/conflict", wantStatus: 409, wantCode: "conflict", wantMessage: "item changed"},
+		{name: "plain error", method: "GET", path: "/items/broken", wantStatus: 500, wantCode: "internal_error", wantMessage: "store down"},
+		{name: "no route", method: "GET", path: "/nothing", wantStatus: 404, wantCode: "not_found", wantMessage: "no route for /nothing"},
+		{name: "wrong method", method: "PUT", path: "/items/1", wantStatus: 405, wantCode: "method_not_allowed", wantMessage: "PUT is not allowed on /items/1", wantAllow: "DELETE, GET"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, nil)
+			request.Header.Set(RequestIdHeader, "request-1")
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, test.wantStatus)
+			}
+			if allow := recorder.Header().Get("Allow"); allow != test.wantAllow {
+				t.Errorf("Allow = %q, want %q", allow, test.wantAllow)
+			}
+
+			var envelope struct {
+				Error Error `json:"error"`
+			}
+			err := json.Unmarshal(recorder.Body.Bytes(), &envelope)
+			if err != nil {
+				t.Fatalf("%v: %s", err, recorder.Body)
+			}
+			if envelope.Error.Code != test.wantCode || envelope.Error.Message != test.wantMessage || envelope.Error.RequestId != "request-1" {
+				t.Errorf("error = %+v, want %s %q request-1", envelope.Error, test.wantCode, test.wantMessage)
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	rt := New()
+	rt.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		return Text(w, http.StatusOK, Param(r, "id"))
+	})
+	rt.Post("/files/{rest...}", func(w http.ResponseWriter, r *http.Request) error {
+		return Text(w, http.StatusOK, Param(r, "rest"))
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{method: "GET", path: "/items/7", want: "7"},
+		{method: "GET", path: "/items/7/", want: "7"},
+		{method: "POST", path: "/files/a/b/c", want: "a/b/c"},
+		{method: "POST", path: "/files", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, httptest.NewRequest(test.method, test.path, nil))
+
+			if recorder.Code != http.StatusOK || recorder.Body.String() != test.want {
+				t.Errorf("got %d %q, want 200 %q", recorder.Code, recorder.Body, test.want)
+			}
+			if recorder.Header().Get(RequestIdHeader) == "" {
+				t.Error("no request id header")
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mock-server/data"
+	"mock-server/fakegen"
+	"mock-server/mockcliff"
+	"mock-server/mockrules"
+	"mock-server/mocksgw"
+	"mock-server/shared"
+	"sync"
+	"time"
+)
+
+// Scenario scripts a reproducible situation: what the stores hold,
+// how the fake Fineract and Sync Gateway answer and which webhooks arrive when.
+type Scenario struct {
+	Name string `json:"name"`
+	//Seed makes generated Fineract data stable, 0 picks a random one
+	Seed        int64           `json:"seed"`
+	ResetStores bool            `json:"resetStores"`
+	Reads       []data.Document `json:"reads"`
+	Writes      []data.Document `json:"writes"`
+	Fineract    Fineract        `json:"fineract"`
+	SGW         SGW             `json:"sgw"`
+	Webhooks    []Webhook       `json:"webhooks"`
+}
+
+type Fineract struct {
+	Reset    bool               `json:"reset"`
+	Clients  []shared.ClientDTO `json:"clients"`
+	Groups   []shared.GroupDTO  `json:"groups"`
+	Offices  []shared.OfficeDTO `json:"offices"`
+	Generate []Generate         `json:"generate"`
+	//SyncReads mirrors the scenario's Fineract clients and groups into the reads store
+	SyncReads bool             `json:"syncReads"`
+	Rules     []mockrules.Rule `json:"rules"`
+}
+
+// Generate asks for fake clients and groups in an office, e.g. 3 inactive clients in office 240.
+type Generate struct {
+	OfficeId int  `json:"officeId"`
+	Clients  int  `json:"clients"`
+	Groups   int  `json:"groups"`
+	Inactive bool `json:"inactive"`
+}
+
+type SGW struct {
+	Reset bool             `json:"reset"`
+	Rules []mockrules.Rule `json:"rules"`
+}
+
+// Webhook schedules Fineract client webhooks, After and Interval are Go durations like "2s".
+type Webhook struct {
+	ClientId int    `json:"clientId"`
+	Action   string `json:"action"`
+	After    string `json:"after"`
+	Times    int    `json:"times"`
+	Interval string `json:"interval"`
+}
+
+func Load(path string) (Scenario, error) {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return Scenario{}, err
+	}
+
+	var scenario Scenario
+	err = json.Unmarshal(content, &scenario)
+
+	if err != nil {
+		return Scenario{}, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+
+	return scenario, nil
+}
+
+// Runner applies scenarios to the running server. Cliff and SGW are nil when those are not mocked.
+type Runner struct {
+	Data   *data.Service
+	Cliff  *mockcliff.Server
+	SGW    *mocksgw.Server
+	Locale string
+
+	mu      sync.Mutex
+	current string
+	timers  []*time.Timer
+}
+
+func (r *Runner) Current() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Apply switches to scenario, cancelling webhooks still scheduled by the previous one.
+func (r *Runner) Apply(scenario Scenario) error {
+	err := r.validate(scenario)
+
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stopTimers()
+
+	err = r.seedStores(scenario)
+
+	if err != nil {
+		return err
+	}
+
+	if r.Cliff != nil {
+		err = r.applyFineract(scenario)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.SGW != nil {
+		if scenario.SGW.Reset {
+			r.SGW.Reset()
+		}
+		r.SGW.Rules.Replace(scenario.SGW.Rules)
+	}
+
+	for _, webhook := range scenario.Webhooks {
+		r.schedule(webhook)
+	}
+
+	r.current = scenario.Name
+	log.Println("Applied scenario", scenario.Name)
+	return nil
+}
+
+// Stop cancels webhooks the current scenario still has scheduled.
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stopTimers()
+}
+
+func (r *Runner) stopTimers() {
+	for _, timer := range r.timers {
+		timer.Stop()
+	}
+	r.timers = nil
+}
+
+func (r *Runner) validate(scenario Scenario) error {
+	fineract := scenario.Fineract
+	usesFineract := fineract.Reset || len(fineract.Clients) > 0 || len(fineract.Groups) > 0 || len(fineract.Generate) > 0 || len(fineract.Rules) > 0 || len(scenario.Webhooks) > 0
+
+	if usesFineract && r.Cliff == nil {
+		return errors.New("scenario configures Fineract but CLIFF_MODE is not mock")
+	}
+
+	if (scenario.SGW.Reset || len(scenario.SGW.Rules) > 0) && r.SGW == nil {
+		return errors.New("scenario configures Sync Gateway but SGW_MODE is not mock")
+	}
+
+	for _, webhook := range scenario.Webhooks {
+		for _, duration := range []string{webhook.After, webhook.Interval} {
+			if duration == "" {
+				continue
+			}
+			if _, err := time.ParseDuration(duration); err != nil {
+				return fmt.Errorf("invalid webhook duration %q", duration)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) seedStores(scenario Scenario) error {
+	buckets := map[string][]data.Document{"reads": scenario.Reads, "writes": scenario.Writes}
+
+	for bucket, documents := range buckets {
+		store, err := r.Data.Store(bucket)
+
+		if err != nil {
+			return err
+		}
+
+		if scenario.ResetStores {
+			existing, err := store.Query("")
+
+			if err != nil {
+				return err
+			}
+
+			for _, document := range existing {
+				err = store.Remove(document.Id)
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, document := range documents {
+			err = store.Upsert(document.Id, document.Content)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) applyFineract(scenario Scenario) error {
+	fineract := scenario.Fineract
+
+	if fineract.Reset {
+		r.Cliff.Reset()
+	}
+
+	r.Cliff.AddFixture(mockcliff.Fixture{Clients: fineract.Clients, Groups: fineract.Groups, Offices: fineract.Offices})
+
+	clients := append([]shared.ClientDTO{}, fineract.Clients...)
+	groups := append([]shared.GroupDTO{}, fineract.Groups...)
+
+	if len(fineract.Generate) > 0 {
+		generator, err := fakegen.New(scenario.Seed, r.Locale)
+
+		if err != nil {
+			return err
+		}
+
+		for _, generate := range fineract.Generate {
+			groups = append(groups, r.Cliff.SeedGroups(generator, generate.OfficeId, generate.Groups)...)
+			clients = append(clients, r.Cliff.SeedClients(generator, generate.OfficeId, generate.Clients, !generate.Inactive)...)
+		}
+	}
+
+	r.Cliff.Rules.Replace(fineract.Rules)
+
+	if fineract.SyncReads {
+		r.Data.SaveInitialGroups(groups)
+		r.Data.SaveInitialClients(clients)
+	}
+
+	return nil
+}
+
+func (r *Runner) schedule(webhook Webhook) {
+	after, _ := time.ParseDuration(webhook.After)
+	interval, _ := time.ParseDuration(webhook.Interval)
+
+	action := webhook.Action
+	if action == "" {
+		action = "UPDATE"
+	}
+
+	times := webhook.Times
+	if times <= 0 {
+		times = 1
+	}
+
+	for i := 0; i < times; i++ {
+		delay := after + time.Duration(i)*interval
+		r.timers = append(r.timers, time.AfterFunc(delay, func() {
+			log.Println("Scenario webhook", action, "for client", webhook.ClientId)
+			r.Cliff.SendWebhook(action, webhook.ClientId)
+		}))
+	}
+}
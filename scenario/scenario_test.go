@@ -0,0 +1,99 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"mock-server/data"
+	"mock-server/fakegen"
+	"mock-server/mockcliff"
+	"mock-server/mockrules"
+	"mock-server/mocksgw"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario Scenario
+		mocked   bool
+		wantErr  string
+	}{
+		{name: "stores only", scenario: Scenario{Reads: []data.Document{{Id: "a", Content: json.RawMessage(`{}`)}}}},
+		{name: "fineract without the mock", scenario: Scenario{Fineract: Fineract{Reset: true}}, wantErr: "CLIFF_MODE"},
+		{name: "webhooks without the mock", scenario: Scenario{Webhooks: []Webhook{{ClientId: 1}}}, wantErr: "CLIFF_MODE"},
+		{name: "sync gateway without the mock", scenario: Scenario{SGW: SGW{Rules: []mockrules.Rule{{StatusCode: 500}}}}, wantErr: "SGW_MODE"},
+		{name: "invalid webhook duration", scenario: Scenario{Webhooks: []Webhook{{ClientId: 1, After: "soon"}}}, mocked: true, wantErr: "soon"},
+		{name: "mocked", scenario: Scenario{Fineract: Fineract{Reset: true}, SGW: SGW{Reset: true}}, mocked: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := &Runner{Data: data.NewServiceWithStores(data.NewMemoryStore(), data.NewMemoryStore())}
+			if test.mocked {
+				runner.Cliff = mockcliff.NewServer()
+				runner.SGW = mocksgw.NewServer()
+			}
+
+			err := runner.Apply(test.scenario)
+
+			if test.wantErr == "" && err != nil {
+				t.Fatalf("Apply = %v, want no error", err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Fatalf("Apply = %v, want an error about %s", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	service := data.NewServiceWithStores(data.NewMemoryStore(), data.NewMemoryStore())
+	runner := &Runner{Data: service, Cliff: mockcliff.NewServer(), SGW: mocksgw.NewServer(), Locale: fakegen.DefaultLocale}
+
+	err := service.Reads.Upsert("stale", map[string]string{"type": "clients"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = runner.Apply(Scenario{
+		Name:        "office 240",
+		Seed:        7,
+		ResetStores: true,
+		Writes:      []data.Document{{Id: "request_1", Content: json.RawMessage(`{"verb":"POST"}`)}},
+		Fineract: Fineract{
+			Reset:     true,
+			Generate:  []Generate{{OfficeId: 240, Clients: 3, Groups: 2}},
+			SyncReads: true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if runner.Current() != "office 240" {
+		t.Errorf("Current = %q, want office 240", runner.Current())
+	}
+
+	var stale map[string]string
+	if err := service.Reads.Get("stale", &stale); !errors.Is(err, data.ErrDocumentNotFound) {
+		t.Errorf("stale document after ResetStores: %v, want ErrDocumentNotFound", err)
+	}
+
+	var request map[string]string
+	if err := service.Writes.Get("request_1", &request); err != nil || request["verb"] != "POST" {
+		t.Errorf("seeded write = %v %v", request, err)
+	}
+
+	clients, err := data.QueryOffice(service.Reads, "clients", 240)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := data.QueryOffice(service.Reads, "groups", 240)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 3 || len(groups) != 2 {
+		t.Errorf("synced reads = %d clients and %d groups, want 3 and 2", len(clients), len(groups))
+	}
+}
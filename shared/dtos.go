@@ -5,16 +5,21 @@ type ClientsResponseDTO struct {
 	PageItems            []ClientDTO `json:"pageItems"`
 }
 
+// ClientUpdateBody changes an existing client, fields left empty are left as they are.
+// Fineract ignores addresses here, they have their own endpoint.
 type ClientUpdateBody struct {
-	ClientAddress  ClientAddress `json:"clientAddress"`
-	LegalFormId    int           `json:"legalFormId"`
-	Firstname      string        `json:"firstname"`
-	Lastname       string        `json:"lastname"`
-	MobileNo       string        `json:"mobileNo"`
-	Locale         string        `json:"locale"`
-	Active         bool          `json:"active"`
-	DateFormat     string        `json:"dateFormat"`
-	ActivationDate string        `json:"activationDate"`
+	LegalFormId            int    `json:"legalFormId,omitempty"`
+	Firstname              string `json:"firstname,omitempty"`
+	Lastname               string `json:"lastname,omitempty"`
+	MobileNo               string `json:"mobileNo,omitempty"`
+	Locale                 string `json:"locale"`
+	Active                 bool   `json:"active"`
+	DateFormat             string `json:"dateFormat"`
+	ActivationDate         string `json:"activationDate,omitempty"`
+	DateOfBirth            string `json:"dateOfBirth,omitempty"`
+	GenderId               int    `json:"genderId,omitempty"`
+	ClientTypeId           int    `json:"clientTypeId,omitempty"`
+	ClientClassificationId int    `json:"clientClassificationId,omitempty"`
 }
 
 type ClientAddress struct {
@@ -31,26 +36,65 @@ type ClientAddress struct {
 	Locale       string `json:"locale"`
 }
 
+// AddressDTO is a client address of Fineract's address module, sent in the address list of
+// a new client and returned by the client addresses endpoint.
+type AddressDTO struct {
+	AddressId       int     `json:"addressId,omitempty"`
+	AddressTypeId   int     `json:"addressTypeId"`
+	AddressType     string  `json:"addressType,omitempty"`
+	IsActive        bool    `json:"isActive"`
+	Street          string  `json:"street,omitempty"`
+	AddressLine1    string  `json:"addressLine1,omitempty"`
+	AddressLine2    string  `json:"addressLine2,omitempty"`
+	AddressLine3    string  `json:"addressLine3,omitempty"`
+	City            string  `json:"city,omitempty"`
+	StateProvinceId int     `json:"stateProvinceId,omitempty"`
+	StateName       string  `json:"stateName,omitempty"`
+	CountryId       int     `json:"countryId,omitempty"`
+	CountryName     string  `json:"countryName,omitempty"`
+	PostalCode      string  `json:"postalCode,omitempty"`
+	Latitude        float64 `json:"latitude,omitempty"`
+	Longitude       float64 `json:"longitude,omitempty"`
+}
+
 type ClientIdentifier struct {
 	DocumentTypeId int    `json:"documentTypeId"`
 	DocumentKey    string `json:"documentKey"`
 	Status         string `json:"status"`
+	Description    string `json:"description,omitempty"`
+}
+
+// IdentifierDTO is a client identifier as the client identifiers endpoint returns it.
+type IdentifierDTO struct {
+	Id           int `json:"id"`
+	ClientId     int `json:"clientId"`
+	DocumentType struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"documentType"`
+	DocumentKey string `json:"documentKey"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
 }
 
 type CreateClientDTO struct {
-	ClientAddress  ClientAddress      `json:"clientAddress"`
-	FamilyMembers  []interface{}      `json:"familyMembers"`
-	OfficeId       int                `json:"officeId"`
-	LegalFormId    int                `json:"legalFormId"`
-	Firstname      string             `json:"firstname"`
-	Lastname       string             `json:"lastname"`
-	MobileNo       string             `json:"mobileNo"`
-	Locale         string             `json:"locale"`
-	Active         bool               `json:"active"`
-	DateFormat     string             `json:"dateFormat"`
-	ActivationDate string             `json:"activationDate"`
-	DateOfBirth    string             `json:"dateOfBirth"`
-	Identifiers    []ClientIdentifier `json:"identifiers"`
+	ClientAddress          ClientAddress      `json:"clientAddress"`
+	FamilyMembers          []interface{}      `json:"familyMembers"`
+	OfficeId               int                `json:"officeId"`
+	LegalFormId            int                `json:"legalFormId"`
+	Firstname              string             `json:"firstname"`
+	Lastname               string             `json:"lastname"`
+	MobileNo               string             `json:"mobileNo"`
+	Locale                 string             `json:"locale"`
+	Active                 bool               `json:"active"`
+	DateFormat             string             `json:"dateFormat"`
+	ActivationDate         string             `json:"activationDate"`
+	DateOfBirth            string             `json:"dateOfBirth,omitempty"`
+	Identifiers            []ClientIdentifier `json:"identifiers"`
+	Address                []AddressDTO       `json:"address,omitempty"`
+	GenderId               int                `json:"genderId,omitempty"`
+	ClientTypeId           int                `json:"clientTypeId,omitempty"`
+	ClientClassificationId int                `json:"clientClassificationId,omitempty"`
 }
 
 type GroupStatus struct {
@@ -120,12 +164,16 @@ type ClientDTO struct {
 		Mandatory bool   `json:"mandatory"`
 	} `json:"gender"`
 	ClientType struct {
-		Active    bool `json:"active"`
-		Mandatory bool `json:"mandatory"`
+		Id        int    `json:"id"`
+		Name      string `json:"name"`
+		Active    bool   `json:"active"`
+		Mandatory bool   `json:"mandatory"`
 	} `json:"clientType"`
 	ClientClassification struct {
-		Active    bool `json:"active"`
-		Mandatory bool `json:"mandatory"`
+		Id        int    `json:"id"`
+		Name      string `json:"name"`
+		Active    bool   `json:"active"`
+		Mandatory bool   `json:"mandatory"`
 	} `json:"clientClassification"`
 	IsStaff    bool   `json:"isStaff"`
 	HasLoans   bool   `json:"hasLoans"`
@@ -157,41 +205,83 @@ type ClientDTO struct {
 		} `json:"mainBusinessLine"`
 	} `json:"clientNonPersonDetails"`
 	CountryId int `json:"countryId"`
+	//Addresses and Identifiers aren't part of Fineract's client, cliff fills them from their own endpoints
+	Addresses   []AddressDTO    `json:"addresses,omitempty"`
+	Identifiers []IdentifierDTO `json:"identifiers,omitempty"`
 }
 
 type ParsedClientRequestBody struct {
-	ClientId      ClientBodyIdentifier `json:"clientId"`
-	ClientBio     ClientBodyBio        `json:"clientBio"`
-	ClientAddress ClientBodyAddress    `json:"clientAddress"`
+	//FineractClientId is set when the request changes an existing client instead of creating one
+	FineractClientId  int                    `json:"fineractClientId"`
+	ClientId          ClientBodyIdentifier   `json:"clientId"`
+	ClientIdentifiers []ClientBodyIdentifier `json:"clientIdentifiers"`
+	ClientBio         ClientBodyBio          `json:"clientBio"`
+	ClientAddress     ClientBodyAddress      `json:"clientAddress"`
 }
 
+// ClientBodyIdentifier is an identifier of a device request. Id names an existing
+// Fineract identifier to update, or to delete when Removed.
 type ClientBodyIdentifier struct {
+	Id             int    `json:"id"`
 	DocumentTypeId int    `json:"documentTypeId"`
+	DocumentType   string `json:"documentType"`
 	DocumentKey    string `json:"documentKey"`
 	Description    string `json:"description"`
+	Status         string `json:"status"`
+	Removed        bool   `json:"removed"`
 }
 
 type ClientBodyBio struct {
-	OfficeId           interface{} `json:"officeId"`
-	Fullname           string      `json:"fullname"`
-	Firstname          string      `json:"firstname"`
-	Lastname           string      `json:"lastname"`
-	GroupId            interface{} `json:"groupId"`
-	DateFormat         string      `json:"dateFormat"`
-	Locale             string      `json:"locale"`
-	Active             bool        `json:"active"`
-	ActivationDate     string      `json:"activationDate"`
-	GenderId           string      `json:"genderId"`
-	PrimaryPhoneNumber string      `json:"primaryPhoneNumber"`
+	OfficeId               interface{} `json:"officeId"`
+	Fullname               string      `json:"fullname"`
+	Firstname              string      `json:"firstname"`
+	Lastname               string      `json:"lastname"`
+	GroupId                interface{} `json:"groupId"`
+	DateFormat             string      `json:"dateFormat"`
+	Locale                 string      `json:"locale"`
+	Active                 bool        `json:"active"`
+	ActivationDate         string      `json:"activationDate"`
+	DateOfBirth            string      `json:"dateOfBirth"`
+	GenderId               string      `json:"genderId"`
+	ClientTypeId           string      `json:"clientTypeId"`
+	ClientClassificationId string      `json:"clientClassificationId"`
+	LegalForm              string      `json:"legalForm"`
+	PrimaryPhoneNumber     string      `json:"primaryPhoneNumber"`
 }
 
 type ClientBodyAddress struct {
-	Street          string `json:"street"`
-	AddressLine1    string `json:"addressLine1"`
-	AddressLine2    string `json:"addressLine2"`
-	AddressLine3    string `json:"addressLine3"`
-	City            string `json:"city"`
-	StateProvinceId int    `json:"stateProvinceId"`
-	CountryId       int    `json:"countryId"`
-	PostalCode      int    `json:"postalCode"`
+	AddressTypeId   int     `json:"addressTypeId"`
+	Street          string  `json:"street"`
+	AddressLine1    string  `json:"addressLine1"`
+	AddressLine2    string  `json:"addressLine2"`
+	AddressLine3    string  `json:"addressLine3"`
+	City            string  `json:"city"`
+	StateProvinceId int     `json:"stateProvinceId"`
+	CountryId       int     `json:"countryId"`
+	PostalCode      int     `json:"postalCode"`
+	Latitude        float64 `json:"latitude"`
+	Longitude       float64 `json:"longitude"`
+}
+
+type OfficeDTO struct {
+	Id            int    `json:"id"`
+	Name          string `json:"name"`
+	NameDecorated string `json:"nameDecorated"`
+	ExternalId    string `json:"externalId"`
+	OpeningDate   []int  `json:"openingDate"`
+	Hierarchy     string `json:"hierarchy"`
+	ParentId      int    `json:"parentId"`
+	ParentName    string `json:"parentName"`
+}
+
+// AuditDTO is an entry of Fineract's audit trail, one per processed command.
+type AuditDTO struct {
+	Id               int    `json:"id"`
+	ActionName       string `json:"actionName"`
+	EntityName       string `json:"entityName"`
+	ResourceId       int    `json:"resourceId"`
+	ClientId         int    `json:"clientId"`
+	OfficeName       string `json:"officeName"`
+	Maker            string `json:"maker"`
+	ProcessingResult string `json:"processingResult"`
 }
@@ -0,0 +1,119 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDraining is returned by Go once the group has started shutting down.
+var ErrDraining = errors.New("shutting down, not accepting background work")
+
+// Group tracks background goroutines (initializations, webhook updates, offline writes)
+// so a shutdown can wait for them instead of dropping them mid-way.
+type Group struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	draining bool
+	running  map[string]int
+}
+
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Group{
+		ctx:     ctx,
+		cancel:  cancel,
+		running: map[string]int{},
+	}
+}
+
+// Go runs fn in a tracked goroutine. The context is cancelled when a drain times out.
+func (g *Group) Go(name string, fn func(ctx context.Context)) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.draining {
+		return fmt.Errorf("%s: %w", name, ErrDraining)
+	}
+
+	g.running[name]++
+	g.wg.Add(1)
+
+	go func() {
+		defer g.done(name)
+		fn(g.ctx)
+	}()
+
+	return nil
+}
+
+func (g *Group) done(name string) {
+	g.mu.Lock()
+	g.running[name]--
+	if g.running[name] == 0 {
+		delete(g.running, name)
+	}
+	g.mu.Unlock()
+
+	g.wg.Done()
+}
+
+// Running lists the names of the goroutines still in flight.
+func (g *Group) Running() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var names []string
+	for name, count := range g.running {
+		for i := 0; i < count; i++ {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cancelGrace is how long a timed out Drain waits for cancelled goroutines to return.
+const cancelGrace = 2 * time.Second
+
+// Drain stops accepting work and waits up to timeout for running goroutines.
+// On timeout their context is cancelled and they get cancelGrace to return,
+// the ones still running after it are reported and left to Running.
+func (g *Group) Drain(timeout time.Duration) error {
+	g.mu.Lock()
+	g.draining = true
+	g.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		g.cancel()
+		return nil
+	case <-time.After(timeout):
+	}
+
+	cancelled := g.Running()
+	g.cancel()
+	log.Println("Drain timed out, cancelled", len(cancelled), "background jobs")
+
+	select {
+	case <-finished:
+		return fmt.Errorf("%d background jobs cancelled after %s: %v", len(cancelled), timeout, cancelled)
+	case <-time.After(cancelGrace):
+	}
+
+	unfinished := g.Running()
+	return fmt.Errorf("%d background jobs still running after %s: %v", len(unfinished), timeout+cancelGrace, unfinished)
+}
@@ -0,0 +1,59 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name        string
+		fn          func(ctx context.Context, release chan struct{})
+		wantErr     bool
+		wantRunning int
+	}{
+		{
+			name: "finishes in time",
+			fn:   func(ctx context.Context, release chan struct{}) { time.Sleep(10 * time.Millisecond) },
+		},
+		{
+			name:    "stops when cancelled",
+			fn:      func(ctx context.Context, release chan struct{}) { <-ctx.Done() },
+			wantErr: true,
+		},
+		{
+			name:        "ignores the cancellation",
+			fn:          func(ctx context.Context, release chan struct{}) { <-release },
+			wantErr:     true,
+			wantRunning: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			group := NewGroup()
+			release := make(chan struct{})
+			defer close(release)
+
+			err := group.Go("job", func(ctx context.Context) { test.fn(ctx, release) })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = group.Drain(50 * time.Millisecond)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Drain = %v, want error %v", err, test.wantErr)
+			}
+			if running := group.Running(); len(running) != test.wantRunning {
+				t.Errorf("Running = %v, want %d", running, test.wantRunning)
+			}
+
+			err = group.Go("late", func(ctx context.Context) {})
+			if !errors.Is(err, ErrDraining) {
+				t.Errorf("Go after Drain = %v, want ErrDraining", err)
+			}
+		})
+	}
+}